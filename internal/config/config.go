@@ -1,12 +1,35 @@
 // Package config with environment variables
 package config
 
-import "github.com/caarlos0/env"
+import (
+	"time"
+
+	"github.com/caarlos0/env"
+)
 
 // Variables is a struct with environment variables
 type Variables struct {
-	PostgresConnBalance string `env:"POSTGRES_CONN_BALANCE"`
-	BalanceAddress      string `env:"BALANCE_ADDRESS"`
+	PostgresConnBalance string        `env:"POSTGRES_CONN_BALANCE"`
+	BalanceAddress      string        `env:"BALANCE_ADDRESS"`
+	MetricsAddress      string        `env:"METRICS_ADDRESS" envDefault:":9090"`
+	TracingEndpoint     string        `env:"TRACING_ENDPOINT"`
+	TracingInsecure     bool          `env:"TRACING_INSECURE"`
+	HealthInterval      time.Duration `env:"HEALTH_INTERVAL" envDefault:"5s"`
+	HealthTimeout       time.Duration `env:"HEALTH_TIMEOUT" envDefault:"2s"`
+	ReconcileInterval   time.Duration `env:"RECONCILE_INTERVAL" envDefault:"1h"`
+	HoldTTL             time.Duration `env:"HOLD_TTL" envDefault:"15m"`
+	HoldExpireInterval  time.Duration `env:"HOLD_EXPIRE_INTERVAL" envDefault:"1m"`
+	ListenRetryInterval time.Duration `env:"LISTEN_RETRY_INTERVAL" envDefault:"5s"`
+	NatsURL             string        `env:"NATS_URL"`
+	NatsTimeout         time.Duration `env:"NATS_TIMEOUT" envDefault:"5s"`
+	OutboxInterval      time.Duration `env:"OUTBOX_INTERVAL" envDefault:"1s"`
+	OutboxBatchSize     int           `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	OutboxMaxAttempts   int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	OutboxRetryDelay    time.Duration `env:"OUTBOX_RETRY_DELAY" envDefault:"1s"`
+	ScheduleInterval    time.Duration `env:"SCHEDULE_INTERVAL" envDefault:"10s"`
+	ScheduleBatchSize   int           `env:"SCHEDULE_BATCH_SIZE" envDefault:"100"`
+	LeaderCheckInterval time.Duration `env:"LEADER_CHECK_INTERVAL" envDefault:"5s"`
+	LogLevel            string        `env:"LOG_LEVEL" envDefault:"info"`
 }
 
 // New returns parsed object of config
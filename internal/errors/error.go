@@ -4,11 +4,69 @@ package errors
 const (
 	// NotEnoughMoney is error code if user don`t have enough money
 	NotEnoughMoney = "NOT_ENOUGH_MONEY"
+	// IdempotencyConflict is error code if operation with the same id but another payload was already recorded
+	IdempotencyConflict = "IDEMPOTENCY_CONFLICT"
+	// InvalidAmount is error code if amount of operation can`t be applied, for example negative amount of transfer
+	InvalidAmount = "INVALID_AMOUNT"
+	// ZeroAmount is error code if amount of operation is zero
+	ZeroAmount = "ZERO_AMOUNT"
+	// SelfTransfer is error code if money is transferred to the same profile
+	SelfTransfer = "SELF_TRANSFER"
+	// AccountFrozen is error code if account of user is frozen
+	AccountFrozen = "ACCOUNT_FROZEN"
+	// AccountClosed is error code if account of user is closed
+	AccountClosed = "ACCOUNT_CLOSED"
+	// LimitExceeded is error code if operation exceeds limit of user
+	LimitExceeded = "LIMIT_EXCEEDED"
+	// DuplicateOperation is error code if operation can be applied only once and was already applied
+	DuplicateOperation = "DUPLICATE_OPERATION"
+	// CurrencyMismatch is error code if currency of operation differs from currency it refers to
+	CurrencyMismatch = "CURRENCY_MISMATCH"
+	// ProfileNotFound is error code if user doesn`t exist
+	ProfileNotFound = "PROFILE_NOT_FOUND"
+	// HoldNotFound is error code if hold doesn`t exist
+	HoldNotFound = "HOLD_NOT_FOUND"
+	// HoldNotActive is error code if hold was already captured, released or expired
+	HoldNotActive = "HOLD_NOT_ACTIVE"
+	// OperationNotFound is error code if operation doesn`t exist
+	OperationNotFound = "OPERATION_NOT_FOUND"
+	// InvalidOperationType is error code if type of operation doesn`t match its amount or can`t be set by client
+	InvalidOperationType = "INVALID_OPERATION_TYPE"
+	// InvalidSchedule is error code if expression of schedule can`t be parsed or runs too often
+	InvalidSchedule = "INVALID_SCHEDULE"
+	// ScheduleNotFound is error code if schedule doesn`t exist
+	ScheduleNotFound = "SCHEDULE_NOT_FOUND"
+	// UnbalancedEntry is error code if postings of journal entry don`t sum to zero
+	UnbalancedEntry = "UNBALANCED_ENTRY"
+	// RunAttemptsExhausted is error code if operation of schedule run failed too many times for reasons other than business rules
+	RunAttemptsExhausted = "RUN_ATTEMPTS_EXHAUSTED"
+)
+
+const (
+	// RequiredKey is metadata key of amount which operation needs
+	RequiredKey = "required"
+	// AvailableKey is metadata key of amount which user has
+	AvailableKey = "available"
+	// CreditLimitKey is metadata key of credit limit of user
+	CreditLimitKey = "creditlimit"
+	// CurrencyKey is metadata key of currency of amounts
+	CurrencyKey = "currency"
+	// RemainingKey is metadata key of amount which is still allowed by limit
+	RemainingKey = "remaining"
+	// PeriodKey is metadata key of period of exceeded limit
+	PeriodKey = "period"
+	// IndexKey is metadata key of index of failed operation in batch
+	IndexKey = "index"
+	// OperationTypeKey is metadata key of type of operation
+	OperationTypeKey = "operationtype"
+	// ExpressionKey is metadata key of expression of schedule
+	ExpressionKey = "expression"
 )
 
 // BusinessError is struct for business errors
 type BusinessError struct {
-	Code string
+	Code     string
+	Metadata map[string]string
 }
 
 // New is constructor for manage business errors
@@ -16,7 +74,22 @@ func New(code string) *BusinessError {
 	return &BusinessError{Code: code}
 }
 
+// WithMetadata adds structured details such as required and available amounts to business error
+func (bs *BusinessError) WithMetadata(key, value string) *BusinessError {
+	if bs.Metadata == nil {
+		bs.Metadata = make(map[string]string)
+	}
+	bs.Metadata[key] = value
+	return bs
+}
+
 // Error is method for creating business errors
 func (bs *BusinessError) Error() string {
 	return bs.Code
 }
+
+// Is reports whether target is business error with the same code, so errors.Is ignores metadata
+func (bs *BusinessError) Is(target error) bool {
+	t, ok := target.(*BusinessError)
+	return ok && t.Code == bs.Code
+}
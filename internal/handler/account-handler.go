@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// accountStatuses maps statuses of accounts in model to responses
+var accountStatuses = map[model.AccountStatus]proto.AccountStatus{
+	model.AccountActive: proto.AccountStatus_ACTIVE,
+	model.AccountFrozen: proto.AccountStatus_FROZEN,
+	model.AccountClosed: proto.AccountStatus_CLOSED,
+}
+
+// FreezeAccount calls FreezeAccount method of Service by handler
+func (b *EntityBalance) FreezeAccount(ctx context.Context, req *proto.FreezeAccountRequest) (*proto.FreezeAccountResponse, error) {
+	change, err := b.parseAccountChange(ctx, req.Profileid, req.Actor, req.Reason)
+	if err != nil {
+		return &proto.FreezeAccountResponse{}, invalidArgument(fmt.Errorf("parseAccountChange %w", err))
+	}
+	state, err := b.srvBalance.FreezeAccount(ctx, change, req.Freezedeposits)
+	if err != nil {
+		return &proto.FreezeAccountResponse{}, toStatus(fmt.Errorf("freezeAccount %w", err))
+	}
+	return &proto.FreezeAccountResponse{
+		Account: protoAccount(state),
+	}, nil
+}
+
+// UnfreezeAccount calls UnfreezeAccount method of Service by handler
+func (b *EntityBalance) UnfreezeAccount(ctx context.Context, req *proto.UnfreezeAccountRequest) (*proto.UnfreezeAccountResponse, error) {
+	change, err := b.parseAccountChange(ctx, req.Profileid, req.Actor, req.Reason)
+	if err != nil {
+		return &proto.UnfreezeAccountResponse{}, invalidArgument(fmt.Errorf("parseAccountChange %w", err))
+	}
+	state, err := b.srvBalance.UnfreezeAccount(ctx, change)
+	if err != nil {
+		return &proto.UnfreezeAccountResponse{}, toStatus(fmt.Errorf("unfreezeAccount %w", err))
+	}
+	return &proto.UnfreezeAccountResponse{
+		Account: protoAccount(state),
+	}, nil
+}
+
+// CloseAccount calls CloseAccount method of Service by handler
+func (b *EntityBalance) CloseAccount(ctx context.Context, req *proto.CloseAccountRequest) (*proto.CloseAccountResponse, error) {
+	change, err := b.parseAccountChange(ctx, req.Profileid, req.Actor, req.Reason)
+	if err != nil {
+		return &proto.CloseAccountResponse{}, invalidArgument(fmt.Errorf("parseAccountChange %w", err))
+	}
+	state, err := b.srvBalance.CloseAccount(ctx, change)
+	if err != nil {
+		return &proto.CloseAccountResponse{}, toStatus(fmt.Errorf("closeAccount %w", err))
+	}
+	return &proto.CloseAccountResponse{
+		Account: protoAccount(state),
+	}, nil
+}
+
+// parseAccountChange validates who changes state of profile and why
+func (b *EntityBalance) parseAccountChange(ctx context.Context, profileID, actor, reason string) (*model.AccountChange, error) {
+	err := b.validate.VarCtx(ctx, profileID, "required,uuid")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	profileUUID, err := uuid.Parse(profileID)
+	if err != nil {
+		return nil, fmt.Errorf("parse %w", err)
+	}
+	err = b.validate.VarCtx(ctx, actor, "required,max=128")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	err = b.validate.VarCtx(ctx, reason, "required,max=1024")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	return &model.AccountChange{
+		ProfileID: profileUUID,
+		Actor:     actor,
+		Reason:    reason,
+	}, nil
+}
+
+// protoAccount converts state of profile to response
+func protoAccount(state *model.AccountState) *proto.Account {
+	return &proto.Account{
+		Profileid:      state.ProfileID.String(),
+		Status:         accountStatuses[state.Status],
+		Freezedeposits: state.FreezeDeposits,
+		Updatedtime:    timestamppb.New(state.UpdatedTime),
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/handler/mocks"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFreezeAccount(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("FreezeAccount", mock.Anything, mock.MatchedBy(func(change *model.AccountChange) bool {
+		return change.ProfileID == testBalance.ProfileID && change.Actor == "compliance" && change.Reason == "investigation"
+	}), true).Return(&model.AccountState{
+		ProfileID:      testBalance.ProfileID,
+		Status:         model.AccountFrozen,
+		FreezeDeposits: true,
+	}, nil).Once()
+	resp, err := hndl.FreezeAccount(context.Background(), &proto.FreezeAccountRequest{
+		Profileid:      testBalance.ProfileID.String(),
+		Actor:          "compliance",
+		Reason:         "investigation",
+		Freezedeposits: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, proto.AccountStatus_FROZEN, resp.Account.Status)
+	require.True(t, resp.Account.Freezedeposits)
+	_, err = hndl.FreezeAccount(context.Background(), &proto.FreezeAccountRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Actor:     "compliance",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
+
+func TestUnfreezeAndCloseAccount(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("UnfreezeAccount", mock.Anything, mock.AnythingOfType("*model.AccountChange")).
+		Return(nil, berrors.New(berrors.AccountClosed)).Once()
+	_, err := hndl.UnfreezeAccount(context.Background(), &proto.UnfreezeAccountRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Actor:     "compliance",
+		Reason:    "resolved",
+	})
+	requireBusinessError(t, err, berrors.AccountClosed)
+	srv.On("CloseAccount", mock.Anything, mock.AnythingOfType("*model.AccountChange")).
+		Return(&model.AccountState{ProfileID: testBalance.ProfileID, Status: model.AccountClosed}, nil).Once()
+	resp, err := hndl.CloseAccount(context.Background(), &proto.CloseAccountRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Actor:     "support",
+		Reason:    "requested by user",
+	})
+	require.NoError(t, err)
+	require.Equal(t, proto.AccountStatus_CLOSED, resp.Account.Status)
+	_, err = hndl.CloseAccount(context.Background(), &proto.CloseAccountRequest{Profileid: "wrong", Actor: "support", Reason: "r"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
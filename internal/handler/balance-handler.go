@@ -3,23 +3,48 @@ package handler
 
 import (
 	"context"
-	"errors"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"time"
 
-	berrors "github.com/artnikel/BalanceService/internal/errors"
 	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
 	"github.com/artnikel/BalanceService/proto"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
-	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultPageSize is the number of operations in a page of history if client doesn`t send it
+	defaultPageSize = 50
 )
 
 // BalanceService is an interface that contains methods of service for balance
 type BalanceService interface {
 	BalanceOperation(ctx context.Context, balance *model.Balance) error
-	GetBalance(ctx context.Context, profileID uuid.UUID) (float64, error)
+	GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error)
+	GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error)
+	ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, *model.Cursor, error)
+	Transfer(ctx context.Context, transfer *model.Transfer) error
+	Hold(ctx context.Context, hold *model.Hold) error
+	GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*model.Balance, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) error
+	ReverseOperation(ctx context.Context, balanceID, reversalID uuid.UUID, description string) (*model.Balance, error)
+	GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error)
+	SetLimit(ctx context.Context, limit *model.Limit) error
+	SetCreditLimit(ctx context.Context, line *model.CreditLine) error
+	FreezeAccount(ctx context.Context, change *model.AccountChange, freezeDeposits bool) (*model.AccountState, error)
+	UnfreezeAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error)
+	CloseAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error)
+	BatchBalanceOperations(ctx context.Context, balances []*model.Balance, atomic bool) ([]*model.BatchOperation, error)
+	ScheduleOperation(ctx context.Context, schedule *model.Schedule) error
+	CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error)
+	ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error)
+	WatchBalance(ctx context.Context, profileID uuid.UUID, currency string, send func(funds *model.Funds) error) error
 }
 
 // EntityBalance contains Balance Service interface
@@ -36,33 +61,73 @@ func NewEntityBalance(srvBalance BalanceService, validate *validator.Validate) *
 
 // BalanceOperation calls BalanceOperation method of Service by handler
 func (b *EntityBalance) BalanceOperation(ctx context.Context, req *proto.BalanceOperationRequest) (*proto.BalanceOperationResponse, error) {
-	profileid := req.Balance.Profileid
-	err := b.validate.VarCtx(ctx, profileid, "required,uuid")
+	createdOperation, err := b.parseBalance(ctx, req.Balance)
 	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &proto.BalanceOperationResponse{}, fmt.Errorf("varCtx %w", err)
+		return &proto.BalanceOperationResponse{}, invalidArgument(fmt.Errorf("parseBalance %w", err))
 	}
-	profileUUID, err := uuid.Parse(profileid)
+	err = b.srvBalance.BalanceOperation(ctx, createdOperation)
 	if err != nil {
-		return &proto.BalanceOperationResponse{}, fmt.Errorf("parse %w", err)
+		return &proto.BalanceOperationResponse{}, toStatus(fmt.Errorf("balanceOperation %w", err))
 	}
-	createdOperation := &model.Balance{
-		BalanceID: uuid.New(),
-		ProfileID: profileUUID,
-		Operation: decimal.NewFromFloat(req.Balance.Operation),
+	return &proto.BalanceOperationResponse{
+		Operation: createdOperation.Operation.String(),
+		Balanceid: createdOperation.BalanceID.String(),
+	}, nil
+}
+
+// parseBalance validates the operation of request and converts it into model
+func (b *EntityBalance) parseBalance(ctx context.Context, balance *proto.Balance) (*model.Balance, error) {
+	ctx, span := tracing.Start(ctx, "handler.parseBalance")
+	defer span.End()
+	err := b.validate.VarCtx(ctx, balance, "required")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
 	}
-	err = b.srvBalance.BalanceOperation(ctx, createdOperation)
+	err = b.validate.VarCtx(ctx, balance.Profileid, "required,uuid")
 	if err != nil {
-		var e *berrors.BusinessError
-		if errors.As(err, &e) {
-			return &proto.BalanceOperationResponse{}, err
-		}
-		logrus.Errorf("error: %v", err)
-		return &proto.BalanceOperationResponse{}, fmt.Errorf("balanceOperations %w", err)
+		return nil, fmt.Errorf("varCtx %w", err)
 	}
-	strOperation := strconv.FormatFloat(req.Balance.Operation, 'f', -1, 64)
-	return &proto.BalanceOperationResponse{
-		Operation: strOperation,
+	profileUUID, err := uuid.Parse(balance.Profileid)
+	if err != nil {
+		return nil, fmt.Errorf("parse %w", err)
+	}
+	balanceUUID, err := balanceIDOrNew(balance.Balanceid)
+	if err != nil {
+		return nil, fmt.Errorf("balanceIDOrNew %w", err)
+	}
+	currency := currencyOrDefault(balance.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	operation, err := parseAmount(balance)
+	if err != nil {
+		return nil, fmt.Errorf("parseAmount %w", err)
+	}
+	err = checkPrecision(operation, currency)
+	if err != nil {
+		return nil, fmt.Errorf("checkPrecision %w", err)
+	}
+	operationType, err := parseOperationType(balance.Type)
+	if err != nil {
+		return nil, fmt.Errorf("parseOperationType %w", err)
+	}
+	err = b.validate.VarCtx(ctx, balance.Description, "max=256")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	err = b.validate.VarCtx(ctx, balance.Metadata, "max=32,dive,keys,required,max=64,endkeys,max=256")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	return &model.Balance{
+		BalanceID:     balanceUUID,
+		ProfileID:     profileUUID,
+		Operation:     operation,
+		Currency:      currency,
+		OperationType: operationType,
+		Description:   balance.Description,
+		Metadata:      balance.Metadata,
 	}, nil
 }
 
@@ -71,20 +136,342 @@ func (b *EntityBalance) GetBalance(ctx context.Context, req *proto.GetBalanceReq
 	id := req.Profileid
 	err := b.validate.VarCtx(ctx, id, "required,uuid")
 	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &proto.GetBalanceResponse{}, fmt.Errorf("varCtx %w", err)
+		return &proto.GetBalanceResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
 	}
 	idUUID, err := uuid.Parse(id)
 	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &proto.GetBalanceResponse{}, fmt.Errorf("parse %w", err)
+		return &proto.GetBalanceResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return &proto.GetBalanceResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	var funds *model.Funds
+	if req.Asof != nil {
+		err = req.Asof.CheckValid()
+		if err != nil {
+			return &proto.GetBalanceResponse{}, invalidArgument(fmt.Errorf("checkValid %w", err))
+		}
+		funds, err = b.srvBalance.GetBalanceAt(ctx, idUUID, currency, req.Asof.AsTime())
+	} else {
+		funds, err = b.srvBalance.GetBalance(ctx, idUUID, currency)
+	}
+	if err != nil {
+		return &proto.GetBalanceResponse{}, toStatus(fmt.Errorf("getBalance %w", err))
+	}
+	resp := &proto.GetBalanceResponse{
+		Money:    funds.Total.InexactFloat64(), //nolint:staticcheck // compatibility with double-based clients
+		Amount:   funds.Total.String(),
+		Currency: currency,
+	}
+	// available balance and credit limit in the past aren`t known, so they are left empty
+	if req.Asof == nil {
+		resp.Available, resp.Creditlimit = funds.Available.String(), funds.CreditLimit.String()
+	}
+	return resp, nil
+}
+
+// WatchBalance calls WatchBalance method of Service by handler and streams balance of profile after every its change
+func (b *EntityBalance) WatchBalance(req *proto.WatchBalanceRequest, stream proto.BalanceService_WatchBalanceServer) error {
+	ctx := stream.Context()
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	err = b.srvBalance.WatchBalance(ctx, profileUUID, currency, func(funds *model.Funds) error {
+		return stream.Send(&proto.WatchBalanceResponse{
+			Amount:    funds.Total.String(),
+			Currency:  currency,
+			Available: funds.Available.String(),
+		})
+	})
+	if err != nil {
+		return toStatus(fmt.Errorf("watchBalance %w", err))
+	}
+	return nil
+}
+
+// balanceIDOrNew parses the id of operation sent by client as idempotency key,
+// operations without it get a new id and can`t be safely retried
+func balanceIDOrNew(balanceID string) (uuid.UUID, error) {
+	if balanceID == "" {
+		return uuid.New(), nil
+	}
+	return uuid.Parse(balanceID)
+}
+
+// checkPrecision checks that amount has no more decimal places than minor units of the currency
+func checkPrecision(amount decimal.Decimal, currency string) error {
+	minorUnits := model.MinorUnits(currency)
+	if !amount.Equal(amount.Truncate(minorUnits)) {
+		return fmt.Errorf("amount %s has more than %d decimal places for %s", amount, minorUnits, currency)
+	}
+	return nil
+}
+
+// idOrEmpty returns id of transfer or reversed operation for operations which refer to them
+func idOrEmpty(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
+// currencyOrDefault returns the default currency for clients which don`t send a currency
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return model.DefaultCurrency
+	}
+	return currency
+}
+
+// operationTypes maps types of operations in requests to model
+var operationTypes = map[proto.OperationType]model.OperationType{
+	proto.OperationType_DEPOSIT:          model.Deposit,
+	proto.OperationType_WITHDRAWAL:       model.Withdrawal,
+	proto.OperationType_TRANSFER:         model.TransferOperation,
+	proto.OperationType_TRADE_SETTLEMENT: model.TradeSettlement,
+	proto.OperationType_FEE:              model.Fee,
+	proto.OperationType_REFUND:           model.Refund,
+	proto.OperationType_BONUS:            model.Bonus,
+	proto.OperationType_HOLD_CAPTURE:     model.HoldCapture,
+	proto.OperationType_REVERSAL:         model.Reversal,
+}
+
+// protoOperationTypes maps types of operations in model to responses
+var protoOperationTypes = func() map[model.OperationType]proto.OperationType {
+	types := make(map[model.OperationType]proto.OperationType, len(operationTypes))
+	for protoType, operationType := range operationTypes {
+		types[operationType] = protoType
+	}
+	return types
+}()
+
+// parseOperationType returns type of operation which client can set, empty type is chosen by sign of amount
+func parseOperationType(protoType proto.OperationType) (model.OperationType, error) {
+	switch protoType {
+	case proto.OperationType_UNSPECIFIED:
+		return "", nil
+	case proto.OperationType_TRANSFER, proto.OperationType_HOLD_CAPTURE, proto.OperationType_REVERSAL:
+		return "", fmt.Errorf("operation type %v is set only by its own method", protoType)
+	}
+	operationType, ok := operationTypes[protoType]
+	if !ok {
+		return "", fmt.Errorf("unknown operation type %v", protoType)
+	}
+	return operationType, nil
+}
+
+// ListOperations calls ListOperations method of Service by handler
+func (b *EntityBalance) ListOperations(ctx context.Context, req *proto.ListOperationsRequest) (*proto.ListOperationsResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
 	}
-	money, err := b.srvBalance.GetBalance(ctx, idUUID)
+	pageSize := int(req.Pagesize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	err = b.validate.VarCtx(ctx, pageSize, "min=1,max=1000")
+	if err != nil {
+		return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	err = b.validate.VarCtx(ctx, int32(req.Sign), "oneof=0 1 2")
+	if err != nil {
+		return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	cursor, err := decodePageToken(req.Pagetoken)
+	if err != nil {
+		return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("decodePageToken %w", err))
+	}
+	types := make([]model.OperationType, 0, len(req.Types))
+	for _, protoType := range req.Types {
+		operationType, ok := operationTypes[protoType]
+		if !ok {
+			return &proto.ListOperationsResponse{}, invalidArgument(fmt.Errorf("unknown operation type %v", protoType))
+		}
+		types = append(types, operationType)
+	}
+	filter := &model.OperationFilter{
+		ProfileID: profileUUID,
+		From:      timeOrZero(req.From),
+		To:        timeOrZero(req.To),
+		Sign:      model.OperationSign(req.Sign),
+		Types:     types,
+		Limit:     pageSize,
+		After:     cursor,
+	}
+	operations, next, err := b.srvBalance.ListOperations(ctx, filter)
+	if err != nil {
+		return &proto.ListOperationsResponse{}, toStatus(fmt.Errorf("listOperations %w", err))
+	}
+	nextPageToken, err := encodePageToken(next)
+	if err != nil {
+		return &proto.ListOperationsResponse{}, toStatus(fmt.Errorf("encodePageToken %w", err))
+	}
+	resp := &proto.ListOperationsResponse{
+		Operations:    make([]*proto.Operation, 0, len(operations)),
+		Nextpagetoken: nextPageToken,
+	}
+	for _, operation := range operations {
+		resp.Operations = append(resp.Operations, protoOperation(operation))
+	}
+	return resp, nil
+}
+
+// ReverseOperation calls ReverseOperation method of Service by handler, empty reversal id is generated
+func (b *EntityBalance) ReverseOperation(ctx context.Context, req *proto.ReverseOperationRequest) (*proto.ReverseOperationResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Balanceid, "required,uuid")
+	if err != nil {
+		return &proto.ReverseOperationResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	balanceUUID, err := uuid.Parse(req.Balanceid)
+	if err != nil {
+		return &proto.ReverseOperationResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	reversalUUID, err := balanceIDOrNew(req.Reversalid)
+	if err != nil {
+		return &proto.ReverseOperationResponse{}, invalidArgument(fmt.Errorf("balanceIDOrNew %w", err))
+	}
+	err = b.validate.VarCtx(ctx, req.Description, "max=256")
+	if err != nil {
+		return &proto.ReverseOperationResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	reversal, err := b.srvBalance.ReverseOperation(ctx, balanceUUID, reversalUUID, req.Description)
+	if err != nil {
+		return &proto.ReverseOperationResponse{}, toStatus(fmt.Errorf("reverseOperation %w", err))
+	}
+	return &proto.ReverseOperationResponse{
+		Operation: protoOperation(reversal),
+	}, nil
+}
+
+// protoOperation converts recorded operation to response
+func protoOperation(operation *model.Balance) *proto.Operation {
+	return &proto.Operation{
+		Balanceid:     operation.BalanceID.String(),
+		Amount:        operation.Operation.String(),
+		Currency:      operation.Currency,
+		Operationtime: timestamppb.New(operation.OperationTime),
+		Transferid:    idOrEmpty(operation.TransferID),
+		Type:          protoOperationTypes[operation.OperationType],
+		Description:   operation.Description,
+		Metadata:      operation.Metadata,
+		Reversalof:    idOrEmpty(operation.ReversalOf),
+	}
+}
+
+// Transfer calls Transfer method of Service by handler
+func (b *EntityBalance) Transfer(ctx context.Context, req *proto.TransferRequest) (*proto.TransferResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Fromprofileid, "required,uuid")
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	fromUUID, err := uuid.Parse(req.Fromprofileid)
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	err = b.validate.VarCtx(ctx, req.Toprofileid, "required,uuid")
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	toUUID, err := uuid.Parse(req.Toprofileid)
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	transferUUID, err := balanceIDOrNew(req.Transferid)
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("balanceIDOrNew %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
 	if err != nil {
-		logrus.Errorf("error: %v", err)
-		return &proto.GetBalanceResponse{}, fmt.Errorf("getBalance %w", err)
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
 	}
-	return &proto.GetBalanceResponse{
-		Money: money,
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("newFromString %w", err))
+	}
+	err = checkPrecision(amount, currency)
+	if err != nil {
+		return &proto.TransferResponse{}, invalidArgument(fmt.Errorf("checkPrecision %w", err))
+	}
+	transfer := &model.Transfer{
+		TransferID:    transferUUID,
+		FromProfileID: fromUUID,
+		ToProfileID:   toUUID,
+		Amount:        amount,
+		Currency:      currency,
+	}
+	err = b.srvBalance.Transfer(ctx, transfer)
+	if err != nil {
+		return &proto.TransferResponse{}, toStatus(fmt.Errorf("transfer %w", err))
+	}
+	return &proto.TransferResponse{
+		Transferid: transfer.TransferID.String(),
 	}, nil
 }
+
+// parseAmount reads the exact decimal amount of operation,
+// clients which still send only the deprecated double field are supported as well
+func parseAmount(balance *proto.Balance) (decimal.Decimal, error) {
+	if balance.Amount == "" {
+		return decimal.NewFromFloat(balance.Operation), nil //nolint:staticcheck // compatibility with double-based clients
+	}
+	amount, err := decimal.NewFromString(balance.Amount)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("newFromString %w", err)
+	}
+	return amount, nil
+}
+
+// timeOrZero converts optional timestamp of request, absent timestamp means that the time range isn`t limited
+func timeOrZero(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// encodePageToken turns the cursor of the next page into opaque token for client, empty token means the last page
+func encodePageToken(cursor *model.Cursor) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+	token, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("marshal %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// decodePageToken reads the cursor from token of previous page, empty token means the first page
+func decodePageToken(pageToken string) (*model.Cursor, error) {
+	if pageToken == "" {
+		return nil, nil
+	}
+	token, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, fmt.Errorf("decodeString %w", err)
+	}
+	cursor := &model.Cursor{}
+	err = json.Unmarshal(token, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %w", err)
+	}
+	return cursor, nil
+}
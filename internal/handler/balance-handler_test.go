@@ -2,8 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
+	berrors "github.com/artnikel/BalanceService/internal/errors"
 	"github.com/artnikel/BalanceService/internal/handler/mocks"
 	"github.com/artnikel/BalanceService/internal/model"
 	"github.com/artnikel/BalanceService/proto"
@@ -12,6 +17,10 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
@@ -19,6 +28,7 @@ var (
 		BalanceID: uuid.New(),
 		ProfileID: uuid.New(),
 		Operation: decimal.NewFromFloat(111.1),
+		Currency:  model.DefaultCurrency,
 	}
 	v = validator.New()
 )
@@ -29,7 +39,7 @@ func TestBalanceOperation(t *testing.T) {
 	protoBalance := &proto.Balance{
 		Balanceid: testBalance.BalanceID.String(),
 		Profileid: testBalance.ProfileID.String(),
-		Operation: testBalance.Operation.InexactFloat64(),
+		Amount:    testBalance.Operation.String(),
 	}
 	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(nil).Once()
 	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
@@ -45,7 +55,7 @@ func TestWrnogBalanceOperation(t *testing.T) {
 	protoBalance := &proto.Balance{
 		Balanceid: testBalance.BalanceID.String(),
 		Profileid: "",
-		Operation: testBalance.Operation.InexactFloat64(),
+		Amount:    testBalance.Operation.String(),
 	}
 	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(nil).Once()
 	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
@@ -60,33 +70,397 @@ func TestGetBalance(t *testing.T) {
 	protoBalance := &proto.Balance{
 		Balanceid: testBalance.BalanceID.String(),
 		Profileid: testBalance.ProfileID.String(),
-		Operation: testBalance.Operation.InexactFloat64(),
+		Amount:    testBalance.Operation.String(),
 	}
 	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(nil).Once()
 	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
 		Balance: protoBalance,
 	})
 	require.NoError(t, err)
-	srv.On("GetBalance", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(testBalance.Operation.InexactFloat64(), nil).Once()
+	srv.On("GetBalance", mock.Anything, mock.AnythingOfType("uuid.UUID"), model.DefaultCurrency).
+		Return(&model.Funds{Total: testBalance.Operation, Available: decimal.NewFromInt(100)}, nil).Once()
 	resp, err := hndl.GetBalance(context.Background(), &proto.GetBalanceRequest{
 		Profileid: protoBalance.Profileid,
 	})
 
-	require.Equal(t, resp.Money, testBalance.Operation.InexactFloat64())
+	require.Equal(t, resp.Amount, testBalance.Operation.String())
+	require.Equal(t, "100", resp.Available)
 	require.NoError(t, err)
 	srv.AssertExpectations(t)
 }
 
+func TestGetBalanceAt(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	asOf := time.Date(2023, time.May, 31, 23, 59, 59, 0, time.UTC)
+	srv.On("GetBalanceAt", mock.Anything, testBalance.ProfileID, model.DefaultCurrency, asOf).
+		Return(&model.Funds{Total: decimal.NewFromInt(70)}, nil).Once()
+	resp, err := hndl.GetBalance(context.Background(), &proto.GetBalanceRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Asof:      timestamppb.New(asOf),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "70", resp.Amount)
+	require.Empty(t, resp.Available)
+	require.Empty(t, resp.Creditlimit)
+	_, err = hndl.GetBalance(context.Background(), &proto.GetBalanceRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Asof:      &timestamppb.Timestamp{Nanos: -1},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
+
 func TestGetBalanceByWrongID(t *testing.T) {
 	srv := new(mocks.BalanceService)
 	hndl := NewEntityBalance(srv, v)
 	protoBalance := &proto.Balance{
 		Profileid: "",
 	}
-	srv.On("GetBalance", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(testBalance.Operation, nil).Once()
+	srv.On("GetBalance", mock.Anything, mock.AnythingOfType("uuid.UUID"), model.DefaultCurrency).
+		Return(&model.Funds{Total: testBalance.Operation, Available: testBalance.Operation}, nil).Once()
 	resp, err := hndl.GetBalance(context.Background(), &proto.GetBalanceRequest{
 		Profileid: protoBalance.Profileid,
 	})
 	require.Error(t, err)
-	require.Equal(t, resp.Money, 0.0)
+	require.Empty(t, resp.Amount)
+}
+
+func TestDoubleBalanceOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	protoBalance := &proto.Balance{
+		Balanceid: testBalance.BalanceID.String(),
+		Profileid: testBalance.ProfileID.String(),
+		Operation: testBalance.Operation.InexactFloat64(),
+	}
+	srv.On("BalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.Operation.Equal(testBalance.Operation)
+	})).Return(nil).Once()
+	resp, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.NoError(t, err)
+	require.Equal(t, resp.Operation, testBalance.Operation.String())
+	srv.AssertExpectations(t)
+}
+
+func TestWrongAmountBalanceOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	protoBalance := &proto.Balance{
+		Balanceid: testBalance.BalanceID.String(),
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    "11.1.1",
+	}
+	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.Error(t, err)
+	srv.AssertNotCalled(t, "BalanceOperation", mock.Anything, mock.Anything)
+}
+
+func TestCurrencyPrecisionBalanceOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	protoBalance := &proto.Balance{
+		Balanceid: testBalance.BalanceID.String(),
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    "100.5",
+		Currency:  "JPY",
+	}
+	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.Error(t, err)
+	protoBalance.Currency = "XYZ"
+	_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.Error(t, err)
+	protoBalance.Currency = "KWD"
+	protoBalance.Amount = "100.125"
+	srv.On("BalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.Currency == "KWD"
+	})).Return(nil).Once()
+	_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.NoError(t, err)
+	srv.AssertExpectations(t)
+}
+
+func TestIdempotentBalanceOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	protoBalance := &proto.Balance{
+		Balanceid: testBalance.BalanceID.String(),
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    testBalance.Operation.String(),
+	}
+	srv.On("BalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.BalanceID == testBalance.BalanceID
+	})).Return(nil).Twice()
+	for i := 0; i < 2; i++ {
+		resp, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+			Balance: protoBalance,
+		})
+		require.NoError(t, err)
+		require.Equal(t, testBalance.BalanceID.String(), resp.Balanceid)
+	}
+	protoBalance.Balanceid = "not-uuid"
+	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: protoBalance,
+	})
+	require.Error(t, err)
+	srv.AssertExpectations(t)
+}
+
+func TestOperationTypeBalanceOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("BalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.OperationType == model.Fee && balance.Description == "monthly fee" && balance.Metadata["plan"] == "pro"
+	})).Return(nil).Once()
+	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{
+		Balance: &proto.Balance{
+			Profileid:   testBalance.ProfileID.String(),
+			Amount:      "-10",
+			Type:        proto.OperationType_FEE,
+			Description: "monthly fee",
+			Metadata:    map[string]string{"plan": "pro"},
+		},
+	})
+	require.NoError(t, err)
+	for _, protoBalance := range []*proto.Balance{
+		{Profileid: testBalance.ProfileID.String(), Amount: "10", Type: proto.OperationType_TRANSFER},
+		{Profileid: testBalance.ProfileID.String(), Amount: "10", Type: proto.OperationType(100)},
+		{Profileid: testBalance.ProfileID.String(), Amount: "10", Metadata: map[string]string{"": "empty key"}},
+	} {
+		_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{Balance: protoBalance})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	srv.AssertExpectations(t)
+}
+
+func TestListOperations(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	operation := *testBalance
+	operation.OperationType = model.Deposit
+	operation.OperationTime = time.Date(2023, time.August, 1, 12, 0, 0, 0, time.UTC)
+	cursor := &model.Cursor{OperationTime: operation.OperationTime, BalanceID: operation.BalanceID}
+	from := time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC)
+	srv.On("ListOperations", mock.Anything, mock.MatchedBy(func(filter *model.OperationFilter) bool {
+		return filter.After == nil && filter.Limit == defaultPageSize && filter.From.Equal(from) && filter.To.IsZero() && filter.Sign == model.Deposits &&
+			len(filter.Types) == 1 && filter.Types[0] == model.Deposit
+	})).Return([]*model.Balance{&operation}, cursor, nil).Once()
+	resp, err := hndl.ListOperations(context.Background(), &proto.ListOperationsRequest{
+		Profileid: testBalance.ProfileID.String(),
+		From:      timestamppb.New(from),
+		Sign:      proto.OperationSign_DEPOSITS,
+		Types:     []proto.OperationType{proto.OperationType_DEPOSIT},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Operations, 1)
+	require.Equal(t, testBalance.Operation.String(), resp.Operations[0].Amount)
+	require.Equal(t, operation.OperationTime, resp.Operations[0].Operationtime.AsTime())
+	require.Equal(t, proto.OperationType_DEPOSIT, resp.Operations[0].Type)
+	require.NotEmpty(t, resp.Nextpagetoken)
+	srv.On("ListOperations", mock.Anything, mock.MatchedBy(func(filter *model.OperationFilter) bool {
+		return filter.After != nil && filter.After.BalanceID == cursor.BalanceID && filter.After.OperationTime.Equal(cursor.OperationTime)
+	})).Return([]*model.Balance{}, nil, nil).Once()
+	resp, err = hndl.ListOperations(context.Background(), &proto.ListOperationsRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Pagetoken: resp.Nextpagetoken,
+	})
+	require.NoError(t, err)
+	require.Empty(t, resp.Operations)
+	require.Empty(t, resp.Nextpagetoken)
+	srv.AssertExpectations(t)
+}
+
+func TestWrongListOperations(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	_, err := hndl.ListOperations(context.Background(), &proto.ListOperationsRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Pagesize:  5000,
+	})
+	require.Error(t, err)
+	_, err = hndl.ListOperations(context.Background(), &proto.ListOperationsRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Pagetoken: "%%%",
+	})
+	require.Error(t, err)
+	_, err = hndl.ListOperations(context.Background(), &proto.ListOperationsRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Types:     []proto.OperationType{proto.OperationType_UNSPECIFIED},
+	})
+	require.Error(t, err)
+	srv.AssertNotCalled(t, "ListOperations", mock.Anything, mock.Anything)
+}
+
+func TestReverseOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	reversal := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     testBalance.ProfileID,
+		Operation:     testBalance.Operation.Neg(),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Reversal,
+		ReversalOf:    uuid.NullUUID{UUID: testBalance.BalanceID, Valid: true},
+	}
+	srv.On("ReverseOperation", mock.Anything, testBalance.BalanceID, reversal.BalanceID, "mistake").Return(reversal, nil).Once()
+	resp, err := hndl.ReverseOperation(context.Background(), &proto.ReverseOperationRequest{
+		Balanceid:   testBalance.BalanceID.String(),
+		Reversalid:  reversal.BalanceID.String(),
+		Description: "mistake",
+	})
+	require.NoError(t, err)
+	require.Equal(t, proto.OperationType_REVERSAL, resp.Operation.Type)
+	require.Equal(t, testBalance.BalanceID.String(), resp.Operation.Reversalof)
+	require.Equal(t, reversal.Operation.String(), resp.Operation.Amount)
+	srv.On("ReverseOperation", mock.Anything, reversal.BalanceID, mock.AnythingOfType("uuid.UUID"), "").
+		Return(nil, berrors.New(berrors.DuplicateOperation)).Once()
+	_, err = hndl.ReverseOperation(context.Background(), &proto.ReverseOperationRequest{Balanceid: reversal.BalanceID.String()})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = hndl.ReverseOperation(context.Background(), &proto.ReverseOperationRequest{Balanceid: "wrong"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
+
+func TestTransfer(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	toProfileID := uuid.New()
+	transferID := uuid.New()
+	srv.On("Transfer", mock.Anything, mock.MatchedBy(func(transfer *model.Transfer) bool {
+		return transfer.TransferID == transferID && transfer.FromProfileID == testBalance.ProfileID &&
+			transfer.ToProfileID == toProfileID && transfer.Amount.Equal(testBalance.Operation)
+	})).Return(nil).Once()
+	resp, err := hndl.Transfer(context.Background(), &proto.TransferRequest{
+		Transferid:    transferID.String(),
+		Fromprofileid: testBalance.ProfileID.String(),
+		Toprofileid:   toProfileID.String(),
+		Amount:        testBalance.Operation.String(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, transferID.String(), resp.Transferid)
+	srv.On("Transfer", mock.Anything, mock.AnythingOfType("*model.Transfer")).Return(berrors.New(berrors.NotEnoughMoney)).Once()
+	_, err = hndl.Transfer(context.Background(), &proto.TransferRequest{
+		Fromprofileid: testBalance.ProfileID.String(),
+		Toprofileid:   toProfileID.String(),
+		Amount:        testBalance.Operation.String(),
+	})
+	requireBusinessError(t, err, berrors.NotEnoughMoney)
+	_, err = hndl.Transfer(context.Background(), &proto.TransferRequest{
+		Fromprofileid: testBalance.ProfileID.String(),
+		Toprofileid:   "",
+		Amount:        testBalance.Operation.String(),
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
+
+func TestBalanceOperationStatuses(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	protoBalance := &proto.Balance{
+		Profileid: "",
+		Amount:    testBalance.Operation.Neg().String(),
+	}
+	_, err := hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{Balance: protoBalance})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	protoBalance.Profileid = testBalance.ProfileID.String()
+	protoBalance.Amount = "1.2.3"
+	_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{Balance: protoBalance})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	protoBalance.Amount = testBalance.Operation.Neg().String()
+	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).
+		Return(fmt.Errorf("checkedBalanceOperation %w", berrors.New(berrors.NotEnoughMoney).
+			WithMetadata(berrors.AvailableKey, "1"))).Once()
+	_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{Balance: protoBalance})
+	info := requireBusinessError(t, err, berrors.NotEnoughMoney)
+	require.Equal(t, "1", info.Metadata[berrors.AvailableKey])
+	srv.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).
+		Return(errors.New("exec unexpected")).Once()
+	_, err = hndl.BalanceOperation(context.Background(), &proto.BalanceOperationRequest{Balance: protoBalance})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Equal(t, "internal error", status.Convert(err).Message())
+	srv.AssertExpectations(t)
+}
+
+func TestToStatus(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{name: "validation", err: fmt.Errorf("varCtx %w", v.Var("", "required")), code: codes.InvalidArgument},
+		{name: "business", err: fmt.Errorf("transfer %w", berrors.New(berrors.NotEnoughMoney)), code: codes.FailedPrecondition},
+		{name: "invalid amount", err: fmt.Errorf("transfer %w", berrors.New(berrors.ZeroAmount)), code: codes.InvalidArgument},
+		{name: "not found", err: fmt.Errorf("getBalance %w", berrors.New(berrors.ProfileNotFound)), code: codes.NotFound},
+		{name: "duplicate", err: fmt.Errorf("balanceOperation %w", berrors.New(berrors.IdempotencyConflict)), code: codes.AlreadyExists},
+		{name: "canceled", err: fmt.Errorf("query %w", context.Canceled), code: codes.Canceled},
+		{name: "deadline", err: fmt.Errorf("query %w", context.DeadlineExceeded), code: codes.DeadlineExceeded},
+		{name: "connection", err: fmt.Errorf("query %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), code: codes.Unavailable},
+		{name: "internal", err: errors.New("scan unexpected"), code: codes.Internal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.code, status.Code(toStatus(tc.err)))
+		})
+	}
+}
+
+func requireBusinessError(t *testing.T, err error, code string) *errdetails.ErrorInfo {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, code, info.Reason)
+	require.Equal(t, ErrorDomain, info.Domain)
+	return info
+}
+
+type watchBalanceStream struct {
+	proto.BalanceService_WatchBalanceServer
+	ctx       context.Context
+	responses []*proto.WatchBalanceResponse
+}
+
+func (s *watchBalanceStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchBalanceStream) Send(resp *proto.WatchBalanceResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func TestWatchBalance(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("WatchBalance", mock.Anything, testBalance.ProfileID, model.DefaultCurrency, mock.Anything).
+		Return(func(_ context.Context, _ uuid.UUID, _ string, send func(*model.Funds) error) error {
+			err := send(&model.Funds{Total: decimal.NewFromInt(100), Available: decimal.NewFromInt(80)})
+			if err != nil {
+				return err
+			}
+			return context.Canceled
+		}).Once()
+	stream := &watchBalanceStream{ctx: context.Background()}
+	err := hndl.WatchBalance(&proto.WatchBalanceRequest{Profileid: testBalance.ProfileID.String()}, stream)
+	require.Equal(t, codes.Canceled, status.Code(err))
+	require.Len(t, stream.responses, 1)
+	require.Equal(t, "100", stream.responses[0].Amount)
+	require.Equal(t, "80", stream.responses[0].Available)
+	require.Equal(t, model.DefaultCurrency, stream.responses[0].Currency)
+	err = hndl.WatchBalance(&proto.WatchBalanceRequest{Profileid: "wrong"}, stream)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
 }
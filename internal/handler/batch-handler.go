@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize is the maximum number of operations in one batch
+const maxBatchSize = 1000
+
+// BatchBalanceOperations calls BatchBalanceOperations method of Service by handler, in atomic mode the error
+// of the first failed operation is returned, otherwise every operation gets its own result
+func (b *EntityBalance) BatchBalanceOperations(ctx context.Context, req *proto.BatchBalanceOperationsRequest) (*proto.BatchBalanceOperationsResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Balances, fmt.Sprintf("min=1,max=%d", maxBatchSize))
+	if err != nil {
+		return &proto.BatchBalanceOperationsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	results := make([]*proto.BatchResult, len(req.Balances))
+	balances := make([]*model.Balance, 0, len(req.Balances))
+	indexes := make([]int, 0, len(req.Balances))
+	for i, balance := range req.Balances {
+		parsed, err := b.parseBalance(ctx, balance)
+		if err != nil {
+			if req.Atomic {
+				return &proto.BatchBalanceOperationsResponse{}, invalidArgument(fmt.Errorf("parseBalance %d %w", i, err))
+			}
+			results[i] = &proto.BatchResult{
+				Balanceid: balance.GetBalanceid(),
+				Code:      int32(codes.InvalidArgument),
+				Message:   fmt.Errorf("parseBalance %w", err).Error(),
+			}
+			continue
+		}
+		balances = append(balances, parsed)
+		indexes = append(indexes, i)
+	}
+	if len(balances) > 0 {
+		batch, err := b.srvBalance.BatchBalanceOperations(ctx, balances, req.Atomic)
+		if err != nil {
+			return &proto.BatchBalanceOperationsResponse{}, toStatus(fmt.Errorf("batchBalanceOperations %w", err))
+		}
+		for i, operation := range batch {
+			results[indexes[i]] = batchResult(operation)
+		}
+	}
+	return &proto.BatchBalanceOperationsResponse{Results: results}, nil
+}
+
+// batchResult converts the result of operation of batch into proto, failed operation has status code
+// and code of business error like the error of single operation
+func batchResult(operation *model.BatchOperation) *proto.BatchResult {
+	result := &proto.BatchResult{
+		Balanceid: operation.Balance.BalanceID.String(),
+		Amount:    operation.Balance.Operation.String(),
+	}
+	if operation.Err == nil {
+		return result
+	}
+	st := status.Convert(toStatus(operation.Err))
+	result.Code = int32(st.Code())
+	result.Message = st.Message()
+	var businessErr *berrors.BusinessError
+	if errors.As(operation.Err, &businessErr) {
+		result.Errorcode = businessErr.Code
+		result.Metadata = businessErr.Metadata
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/handler/mocks"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBatchBalanceOperations(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	deposit, withdrawal := uuid.New(), uuid.New()
+	srv.On("BatchBalanceOperations", mock.Anything, mock.MatchedBy(func(balances []*model.Balance) bool {
+		return len(balances) == 2 && balances[0].BalanceID == deposit && balances[1].BalanceID == withdrawal
+	}), false).Return(func(_ context.Context, balances []*model.Balance, _ bool) []*model.BatchOperation {
+		return []*model.BatchOperation{
+			{Balance: balances[0]},
+			{Balance: balances[1], Err: berrors.New(berrors.NotEnoughMoney).WithMetadata(berrors.RequiredKey, "500")},
+		}
+	}, nil).Once()
+	resp, err := hndl.BatchBalanceOperations(context.Background(), &proto.BatchBalanceOperationsRequest{Balances: []*proto.Balance{
+		{Balanceid: deposit.String(), Profileid: testBalance.ProfileID.String(), Amount: "100"},
+		{Balanceid: uuid.NewString(), Profileid: "wrong", Amount: "100"},
+		{Balanceid: withdrawal.String(), Profileid: testBalance.ProfileID.String(), Amount: "-500"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	require.Equal(t, int32(codes.OK), resp.Results[0].Code)
+	require.Equal(t, "100", resp.Results[0].Amount)
+	require.Equal(t, int32(codes.InvalidArgument), resp.Results[1].Code)
+	require.Equal(t, int32(codes.FailedPrecondition), resp.Results[2].Code)
+	require.Equal(t, berrors.NotEnoughMoney, resp.Results[2].Errorcode)
+	require.Equal(t, "500", resp.Results[2].Metadata[berrors.RequiredKey])
+	srv.AssertExpectations(t)
+}
+
+func TestAtomicBatchBalanceOperations(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("BatchBalanceOperations", mock.Anything, mock.Anything, true).
+		Return(nil, berrors.New(berrors.NotEnoughMoney).WithMetadata(berrors.IndexKey, "1")).Once()
+	balances := []*proto.Balance{
+		{Profileid: testBalance.ProfileID.String(), Amount: "100"},
+		{Profileid: testBalance.ProfileID.String(), Amount: "-500"},
+	}
+	_, err := hndl.BatchBalanceOperations(context.Background(), &proto.BatchBalanceOperationsRequest{Balances: balances, Atomic: true})
+	require.Equal(t, "1", requireBusinessError(t, err, berrors.NotEnoughMoney).Metadata[berrors.IndexKey])
+
+	balances[1].Amount = "many"
+	_, err = hndl.BatchBalanceOperations(context.Background(), &proto.BatchBalanceOperationsRequest{Balances: balances, Atomic: true})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = hndl.BatchBalanceOperations(context.Background(), &proto.BatchBalanceOperationsRequest{Atomic: true})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
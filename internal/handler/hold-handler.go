@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Hold calls Hold method of Service by handler
+func (b *EntityBalance) Hold(ctx context.Context, req *proto.HoldRequest) (*proto.HoldResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	holdUUID, err := balanceIDOrNew(req.Holdid)
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("balanceIDOrNew %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("newFromString %w", err))
+	}
+	err = checkPrecision(amount, currency)
+	if err != nil {
+		return &proto.HoldResponse{}, invalidArgument(fmt.Errorf("checkPrecision %w", err))
+	}
+	hold := &model.Hold{
+		HoldID:    holdUUID,
+		ProfileID: profileUUID,
+		Amount:    amount,
+		Currency:  currency,
+	}
+	err = b.srvBalance.Hold(ctx, hold)
+	if err != nil {
+		return &proto.HoldResponse{}, toStatus(fmt.Errorf("hold %w", err))
+	}
+	return &proto.HoldResponse{
+		Holdid:      hold.HoldID.String(),
+		Expirestime: timestamppb.New(hold.ExpiresTime),
+	}, nil
+}
+
+// Capture calls CaptureHold method of Service by handler, empty amount captures the whole hold,
+// amount can`t be negative or more precise than the currency of hold
+func (b *EntityBalance) Capture(ctx context.Context, req *proto.CaptureRequest) (*proto.CaptureResponse, error) {
+	holdUUID, err := b.parseHoldID(ctx, req.Holdid)
+	if err != nil {
+		return &proto.CaptureResponse{}, invalidArgument(fmt.Errorf("parseHoldID %w", err))
+	}
+	amount := decimal.Zero
+	if req.Amount != "" {
+		amount, err = decimal.NewFromString(req.Amount)
+		if err != nil {
+			return &proto.CaptureResponse{}, invalidArgument(fmt.Errorf("newFromString %w", err))
+		}
+		if amount.IsNegative() {
+			err = fmt.Errorf("amount %s is negative", amount)
+			return &proto.CaptureResponse{}, invalidArgument(err)
+		}
+		hold, err := b.srvBalance.GetHold(ctx, holdUUID)
+		if err != nil {
+			return &proto.CaptureResponse{}, toStatus(fmt.Errorf("getHold %w", err))
+		}
+		err = checkPrecision(amount, hold.Currency)
+		if err != nil {
+			return &proto.CaptureResponse{}, invalidArgument(fmt.Errorf("checkPrecision %w", err))
+		}
+	}
+	operation, err := b.srvBalance.CaptureHold(ctx, holdUUID, amount)
+	if err != nil {
+		return &proto.CaptureResponse{}, toStatus(fmt.Errorf("captureHold %w", err))
+	}
+	return &proto.CaptureResponse{
+		Balanceid: operation.BalanceID.String(),
+		Amount:    operation.Operation.String(),
+	}, nil
+}
+
+// Release calls ReleaseHold method of Service by handler
+func (b *EntityBalance) Release(ctx context.Context, req *proto.ReleaseRequest) (*proto.ReleaseResponse, error) {
+	holdUUID, err := b.parseHoldID(ctx, req.Holdid)
+	if err != nil {
+		return &proto.ReleaseResponse{}, invalidArgument(fmt.Errorf("parseHoldID %w", err))
+	}
+	err = b.srvBalance.ReleaseHold(ctx, holdUUID)
+	if err != nil {
+		return &proto.ReleaseResponse{}, toStatus(fmt.Errorf("releaseHold %w", err))
+	}
+	return &proto.ReleaseResponse{
+		Holdid: holdUUID.String(),
+	}, nil
+}
+
+// parseHoldID validates and parses the id of existing hold
+func (b *EntityBalance) parseHoldID(ctx context.Context, holdID string) (uuid.UUID, error) {
+	err := b.validate.VarCtx(ctx, holdID, "required,uuid")
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("varCtx %w", err)
+	}
+	holdUUID, err := uuid.Parse(holdID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("parse %w", err)
+	}
+	return holdUUID, nil
+}
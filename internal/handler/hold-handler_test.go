@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/handler/mocks"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHold(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	holdID := uuid.New()
+	expiresTime := time.Now().Add(time.Minute).UTC()
+	srv.On("Hold", mock.Anything, mock.MatchedBy(func(hold *model.Hold) bool {
+		return hold.HoldID == holdID && hold.ProfileID == testBalance.ProfileID && hold.Amount.Equal(testBalance.Operation)
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.Hold).ExpiresTime = expiresTime
+	}).Return(nil).Once()
+	resp, err := hndl.Hold(context.Background(), &proto.HoldRequest{
+		Holdid:    holdID.String(),
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    testBalance.Operation.String(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, holdID.String(), resp.Holdid)
+	require.True(t, expiresTime.Equal(resp.Expirestime.AsTime()))
+	srv.On("Hold", mock.Anything, mock.AnythingOfType("*model.Hold")).Return(berrors.New(berrors.NotEnoughMoney)).Once()
+	_, err = hndl.Hold(context.Background(), &proto.HoldRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    testBalance.Operation.String(),
+	})
+	requireBusinessError(t, err, berrors.NotEnoughMoney)
+	_, err = hndl.Hold(context.Background(), &proto.HoldRequest{
+		Profileid: testBalance.ProfileID.String(),
+		Amount:    "1.001",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
+
+func TestCaptureAndRelease(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	holdID := uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromInt(-50),
+		Currency:  model.DefaultCurrency,
+	}
+	srv.On("CaptureHold", mock.Anything, holdID, decimal.Zero).Return(operation, nil).Once()
+	resp, err := hndl.Capture(context.Background(), &proto.CaptureRequest{Holdid: holdID.String()})
+	require.NoError(t, err)
+	require.Equal(t, operation.BalanceID.String(), resp.Balanceid)
+	require.Equal(t, "-50", resp.Amount)
+	srv.On("GetHold", mock.Anything, holdID).Return(&model.Hold{HoldID: holdID, Currency: "USD"}, nil).Times(2)
+	srv.On("CaptureHold", mock.Anything, holdID, mock.AnythingOfType("decimal.Decimal")).
+		Return(nil, berrors.New(berrors.HoldNotActive)).Once()
+	_, err = hndl.Capture(context.Background(), &proto.CaptureRequest{Holdid: holdID.String(), Amount: "10"})
+	requireBusinessError(t, err, berrors.HoldNotActive)
+	_, err = hndl.Capture(context.Background(), &proto.CaptureRequest{Holdid: holdID.String(), Amount: "0.001"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = hndl.Capture(context.Background(), &proto.CaptureRequest{Holdid: holdID.String(), Amount: "-10"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.On("ReleaseHold", mock.Anything, holdID).Return(berrors.New(berrors.HoldNotFound)).Once()
+	_, err = hndl.Release(context.Background(), &proto.ReleaseRequest{Holdid: holdID.String()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = hndl.Release(context.Background(), &proto.ReleaseRequest{Holdid: "wrong"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
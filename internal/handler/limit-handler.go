@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// limitDirections maps directions of limits in requests to model
+var limitDirections = map[proto.LimitDirection]model.LimitDirection{
+	proto.LimitDirection_DEPOSIT_LIMIT:    model.DepositLimit,
+	proto.LimitDirection_WITHDRAWAL_LIMIT: model.WithdrawalLimit,
+}
+
+// limitPeriods maps periods of limits in requests to model
+var limitPeriods = map[proto.LimitPeriod]model.LimitPeriod{
+	proto.LimitPeriod_PER_OPERATION: model.PerOperation,
+	proto.LimitPeriod_DAILY:         model.Daily,
+	proto.LimitPeriod_MONTHLY:       model.Monthly,
+}
+
+// GetLimits calls GetLimits method of Service by handler
+func (b *EntityBalance) GetLimits(ctx context.Context, req *proto.GetLimitsRequest) (*proto.GetLimitsResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return &proto.GetLimitsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return &proto.GetLimitsResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return &proto.GetLimitsResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	limits, err := b.srvBalance.GetLimits(ctx, profileUUID, currency)
+	if err != nil {
+		return &proto.GetLimitsResponse{}, toStatus(fmt.Errorf("getLimits %w", err))
+	}
+	resp := &proto.GetLimitsResponse{
+		Limits: make([]*proto.Limit, 0, len(limits)),
+	}
+	for _, limit := range limits {
+		resp.Limits = append(resp.Limits, protoLimit(limit))
+	}
+	return resp, nil
+}
+
+// SetLimit calls SetLimit method of Service by handler, limit without profile id is the default limit
+func (b *EntityBalance) SetLimit(ctx context.Context, req *proto.SetLimitRequest) (*proto.SetLimitResponse, error) {
+	if req.Limit == nil {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("empty limit"))
+	}
+	profileUUID := model.DefaultLimitProfileID
+	if req.Limit.Profileid != "" {
+		err := b.validate.VarCtx(ctx, req.Limit.Profileid, "uuid")
+		if err != nil {
+			return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+		}
+		profileUUID, err = uuid.Parse(req.Limit.Profileid)
+		if err != nil {
+			return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+		}
+	}
+	currency := currencyOrDefault(req.Limit.Currency)
+	err := b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	direction, ok := limitDirections[req.Limit.Direction]
+	if !ok {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("unknown limit direction %v", req.Limit.Direction))
+	}
+	period, ok := limitPeriods[req.Limit.Period]
+	if !ok {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("unknown limit period %v", req.Limit.Period))
+	}
+	amount, err := decimal.NewFromString(req.Limit.Amount)
+	if err != nil {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("newFromString %w", err))
+	}
+	err = checkPrecision(amount, currency)
+	if err != nil {
+		return &proto.SetLimitResponse{}, invalidArgument(fmt.Errorf("checkPrecision %w", err))
+	}
+	limit := &model.Limit{
+		ProfileID: profileUUID,
+		Currency:  currency,
+		Direction: direction,
+		Period:    period,
+		Amount:    amount,
+	}
+	err = b.srvBalance.SetLimit(ctx, limit)
+	if err != nil {
+		return &proto.SetLimitResponse{}, toStatus(fmt.Errorf("setLimit %w", err))
+	}
+	return &proto.SetLimitResponse{
+		Limit: protoLimit(limit),
+	}, nil
+}
+
+// SetCreditLimit calls SetCreditLimit method of Service by handler
+func (b *EntityBalance) SetCreditLimit(ctx context.Context, req *proto.SetCreditLimitRequest) (*proto.SetCreditLimitResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	currency := currencyOrDefault(req.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	creditLimit, err := decimal.NewFromString(req.Creditlimit)
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, invalidArgument(fmt.Errorf("newFromString %w", err))
+	}
+	err = checkPrecision(creditLimit, currency)
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, invalidArgument(fmt.Errorf("checkPrecision %w", err))
+	}
+	line := &model.CreditLine{
+		ProfileID:   profileUUID,
+		Currency:    currency,
+		CreditLimit: creditLimit,
+	}
+	err = b.srvBalance.SetCreditLimit(ctx, line)
+	if err != nil {
+		return &proto.SetCreditLimitResponse{}, toStatus(fmt.Errorf("setCreditLimit %w", err))
+	}
+	return &proto.SetCreditLimitResponse{
+		Profileid:   line.ProfileID.String(),
+		Currency:    line.Currency,
+		Creditlimit: line.CreditLimit.String(),
+		Updatedtime: timestamppb.New(line.UpdatedTime),
+	}, nil
+}
+
+// protoLimit converts limit to response
+func protoLimit(limit *model.Limit) *proto.Limit {
+	protoLimit := &proto.Limit{
+		Currency:    limit.Currency,
+		Amount:      limit.Amount.String(),
+		Isdefault:   limit.IsDefault(),
+		Updatedtime: timestamppb.New(limit.UpdatedTime),
+	}
+	if !limit.IsDefault() {
+		protoLimit.Profileid = limit.ProfileID.String()
+	}
+	for protoDirection, direction := range limitDirections {
+		if direction == limit.Direction {
+			protoLimit.Direction = protoDirection
+		}
+	}
+	for protoPeriod, period := range limitPeriods {
+		if period == limit.Period {
+			protoLimit.Period = protoPeriod
+		}
+	}
+	return protoLimit
+}
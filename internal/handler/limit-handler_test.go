@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/handler/mocks"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetLimit(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("SetLimit", mock.Anything, mock.MatchedBy(func(limit *model.Limit) bool {
+		return limit.IsDefault() && limit.Direction == model.WithdrawalLimit && limit.Period == model.Daily && limit.Amount.String() == "1000"
+	})).Return(nil).Once()
+	resp, err := hndl.SetLimit(context.Background(), &proto.SetLimitRequest{Limit: &proto.Limit{
+		Direction: proto.LimitDirection_WITHDRAWAL_LIMIT,
+		Period:    proto.LimitPeriod_DAILY,
+		Amount:    "1000",
+	}})
+	require.NoError(t, err)
+	require.True(t, resp.Limit.Isdefault)
+	require.Empty(t, resp.Limit.Profileid)
+	require.Equal(t, proto.LimitPeriod_DAILY, resp.Limit.Period)
+	for _, limit := range []*proto.Limit{
+		{Direction: proto.LimitDirection_DIRECTION_UNSPECIFIED, Period: proto.LimitPeriod_DAILY, Amount: "1"},
+		{Direction: proto.LimitDirection_DEPOSIT_LIMIT, Period: proto.LimitPeriod_PERIOD_UNSPECIFIED, Amount: "1"},
+		{Direction: proto.LimitDirection_DEPOSIT_LIMIT, Period: proto.LimitPeriod_DAILY, Amount: "1", Profileid: "wrong"},
+		{Direction: proto.LimitDirection_DEPOSIT_LIMIT, Period: proto.LimitPeriod_DAILY, Amount: "0.001"},
+	} {
+		_, err = hndl.SetLimit(context.Background(), &proto.SetLimitRequest{Limit: limit})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	srv.AssertExpectations(t)
+}
+
+func TestGetLimits(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("GetLimits", mock.Anything, testBalance.ProfileID, model.DefaultCurrency).Return([]*model.Limit{{
+		ProfileID: testBalance.ProfileID,
+		Currency:  model.DefaultCurrency,
+		Direction: model.DepositLimit,
+		Period:    model.Monthly,
+		Amount:    decimal.NewFromInt(5000),
+	}}, nil).Once()
+	resp, err := hndl.GetLimits(context.Background(), &proto.GetLimitsRequest{Profileid: testBalance.ProfileID.String()})
+	require.NoError(t, err)
+	require.Len(t, resp.Limits, 1)
+	require.Equal(t, testBalance.ProfileID.String(), resp.Limits[0].Profileid)
+	require.Equal(t, proto.LimitDirection_DEPOSIT_LIMIT, resp.Limits[0].Direction)
+	require.Equal(t, proto.LimitPeriod_MONTHLY, resp.Limits[0].Period)
+	require.False(t, resp.Limits[0].Isdefault)
+	srv.AssertExpectations(t)
+}
+
+func TestSetCreditLimit(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("SetCreditLimit", mock.Anything, mock.MatchedBy(func(line *model.CreditLine) bool {
+		return line.ProfileID == testBalance.ProfileID && line.CreditLimit.String() == "250.5"
+	})).Return(nil).Once()
+	resp, err := hndl.SetCreditLimit(context.Background(), &proto.SetCreditLimitRequest{
+		Profileid:   testBalance.ProfileID.String(),
+		Creditlimit: "250.5",
+	})
+	require.NoError(t, err)
+	require.Equal(t, model.DefaultCurrency, resp.Currency)
+	require.Equal(t, "250.5", resp.Creditlimit)
+	_, err = hndl.SetCreditLimit(context.Background(), &proto.SetCreditLimitRequest{
+		Profileid:   testBalance.ProfileID.String(),
+		Creditlimit: "many",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
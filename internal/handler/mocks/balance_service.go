@@ -5,11 +5,15 @@ package mocks
 import (
 	context "context"
 
-	mock "github.com/stretchr/testify/mock"
-
 	model "github.com/artnikel/BalanceService/internal/model"
 
 	uuid "github.com/google/uuid"
+
+	decimal "github.com/shopspring/decimal"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // BalanceService is an autogenerated mock type for the BalanceService type
@@ -31,15 +35,270 @@ func (_m *BalanceService) BalanceOperation(ctx context.Context, balance *model.B
 	return r0
 }
 
-// GetBalance provides a mock function with given fields: ctx, profileID
-func (_m *BalanceService) GetBalance(ctx context.Context, profileID uuid.UUID) (float64, error) {
+// BatchBalanceOperations provides a mock function with given fields: ctx, balances, atomic
+func (_m *BalanceService) BatchBalanceOperations(ctx context.Context, balances []*model.Balance, atomic bool) ([]*model.BatchOperation, error) {
+	ret := _m.Called(ctx, balances, atomic)
+
+	var r0 []*model.BatchOperation
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.Balance, bool) []*model.BatchOperation); ok {
+		r0 = rf(ctx, balances, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BatchOperation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*model.Balance, bool) error); ok {
+		r1 = rf(ctx, balances, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CancelSchedule provides a mock function with given fields: ctx, scheduleID
+func (_m *BalanceService) CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error) {
+	ret := _m.Called(ctx, scheduleID)
+
+	var r0 *model.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *model.Schedule); ok {
+		r0 = rf(ctx, scheduleID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, scheduleID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CaptureHold provides a mock function with given fields: ctx, holdID, amount
+func (_m *BalanceService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*model.Balance, error) {
+	ret := _m.Called(ctx, holdID, amount)
+
+	var r0 *model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, decimal.Decimal) *model.Balance); ok {
+		r0 = rf(ctx, holdID, amount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Balance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, decimal.Decimal) error); ok {
+		r1 = rf(ctx, holdID, amount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CloseAccount provides a mock function with given fields: ctx, change
+func (_m *BalanceService) CloseAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error) {
+	ret := _m.Called(ctx, change)
+
+	var r0 *model.AccountState
+	if rf, ok := ret.Get(0).(func(context.Context, *model.AccountChange) *model.AccountState); ok {
+		r0 = rf(ctx, change)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.AccountState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.AccountChange) error); ok {
+		r1 = rf(ctx, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FreezeAccount provides a mock function with given fields: ctx, change, freezeDeposits
+func (_m *BalanceService) FreezeAccount(ctx context.Context, change *model.AccountChange, freezeDeposits bool) (*model.AccountState, error) {
+	ret := _m.Called(ctx, change, freezeDeposits)
+
+	var r0 *model.AccountState
+	if rf, ok := ret.Get(0).(func(context.Context, *model.AccountChange, bool) *model.AccountState); ok {
+		r0 = rf(ctx, change, freezeDeposits)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.AccountState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.AccountChange, bool) error); ok {
+		r1 = rf(ctx, change, freezeDeposits)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalance provides a mock function with given fields: ctx, profileID, currency
+func (_m *BalanceService) GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error) {
+	ret := _m.Called(ctx, profileID, currency)
+
+	var r0 *model.Funds
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) *model.Funds); ok {
+		r0 = rf(ctx, profileID, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Funds)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, profileID, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalanceAt provides a mock function with given fields: ctx, profileID, currency, asOf
+func (_m *BalanceService) GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error) {
+	ret := _m.Called(ctx, profileID, currency, asOf)
+
+	var r0 *model.Funds
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Time) *model.Funds); ok {
+		r0 = rf(ctx, profileID, currency, asOf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Funds)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string, time.Time) error); ok {
+		r1 = rf(ctx, profileID, currency, asOf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHold provides a mock function with given fields: ctx, holdID
+func (_m *BalanceService) GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error) {
+	ret := _m.Called(ctx, holdID)
+
+	var r0 *model.Hold
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *model.Hold); ok {
+		r0 = rf(ctx, holdID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Hold)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, holdID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLimits provides a mock function with given fields: ctx, profileID, currency
+func (_m *BalanceService) GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error) {
+	ret := _m.Called(ctx, profileID, currency)
+
+	var r0 []*model.Limit
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) []*model.Limit); ok {
+		r0 = rf(ctx, profileID, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Limit)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, profileID, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Hold provides a mock function with given fields: ctx, hold
+func (_m *BalanceService) Hold(ctx context.Context, hold *model.Hold) error {
+	ret := _m.Called(ctx, hold)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Hold) error); ok {
+		r0 = rf(ctx, hold)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListOperations provides a mock function with given fields: ctx, filter
+func (_m *BalanceService) ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, *model.Cursor, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, *model.OperationFilter) []*model.Balance); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Balance)
+		}
+	}
+
+	var r1 *model.Cursor
+	if rf, ok := ret.Get(1).(func(context.Context, *model.OperationFilter) *model.Cursor); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*model.Cursor)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *model.OperationFilter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListSchedules provides a mock function with given fields: ctx, profileID
+func (_m *BalanceService) ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error) {
 	ret := _m.Called(ctx, profileID)
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) float64); ok {
+	var r0 []*model.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*model.Schedule); ok {
 		r0 = rf(ctx, profileID)
 	} else {
-		r0 = ret.Get(0).(float64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Schedule)
+		}
 	}
 
 	var r1 error
@@ -52,6 +311,136 @@ func (_m *BalanceService) GetBalance(ctx context.Context, profileID uuid.UUID) (
 	return r0, r1
 }
 
+// ReleaseHold provides a mock function with given fields: ctx, holdID
+func (_m *BalanceService) ReleaseHold(ctx context.Context, holdID uuid.UUID) error {
+	ret := _m.Called(ctx, holdID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, holdID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReverseOperation provides a mock function with given fields: ctx, balanceID, reversalID, description
+func (_m *BalanceService) ReverseOperation(ctx context.Context, balanceID uuid.UUID, reversalID uuid.UUID, description string) (*model.Balance, error) {
+	ret := _m.Called(ctx, balanceID, reversalID, description)
+
+	var r0 *model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, uuid.UUID, string) *model.Balance); ok {
+		r0 = rf(ctx, balanceID, reversalID, description)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Balance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, balanceID, reversalID, description)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScheduleOperation provides a mock function with given fields: ctx, schedule
+func (_m *BalanceService) ScheduleOperation(ctx context.Context, schedule *model.Schedule) error {
+	ret := _m.Called(ctx, schedule)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Schedule) error); ok {
+		r0 = rf(ctx, schedule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetCreditLimit provides a mock function with given fields: ctx, line
+func (_m *BalanceService) SetCreditLimit(ctx context.Context, line *model.CreditLine) error {
+	ret := _m.Called(ctx, line)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.CreditLine) error); ok {
+		r0 = rf(ctx, line)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLimit provides a mock function with given fields: ctx, limit
+func (_m *BalanceService) SetLimit(ctx context.Context, limit *model.Limit) error {
+	ret := _m.Called(ctx, limit)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Limit) error); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Transfer provides a mock function with given fields: ctx, transfer
+func (_m *BalanceService) Transfer(ctx context.Context, transfer *model.Transfer) error {
+	ret := _m.Called(ctx, transfer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Transfer) error); ok {
+		r0 = rf(ctx, transfer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnfreezeAccount provides a mock function with given fields: ctx, change
+func (_m *BalanceService) UnfreezeAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error) {
+	ret := _m.Called(ctx, change)
+
+	var r0 *model.AccountState
+	if rf, ok := ret.Get(0).(func(context.Context, *model.AccountChange) *model.AccountState); ok {
+		r0 = rf(ctx, change)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.AccountState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.AccountChange) error); ok {
+		r1 = rf(ctx, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WatchBalance provides a mock function with given fields: ctx, profileID, currency, send
+func (_m *BalanceService) WatchBalance(ctx context.Context, profileID uuid.UUID, currency string, send func(*model.Funds) error) error {
+	ret := _m.Called(ctx, profileID, currency, send)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, func(*model.Funds) error) error); ok {
+		r0 = rf(ctx, profileID, currency, send)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewBalanceService interface {
 	mock.TestingT
 	Cleanup(func())
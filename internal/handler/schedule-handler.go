@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// scheduleStatuses maps statuses of schedules in model to responses
+var scheduleStatuses = map[model.ScheduleStatus]proto.ScheduleStatus{
+	model.ScheduleActive:    proto.ScheduleStatus_SCHEDULE_ACTIVE,
+	model.ScheduleCancelled: proto.ScheduleStatus_SCHEDULE_CANCELLED,
+}
+
+// runStatuses maps statuses of runs of schedules in model to responses
+var runStatuses = map[model.RunStatus]proto.RunStatus{
+	model.RunSucceeded: proto.RunStatus_RUN_SUCCEEDED,
+	model.RunFailed:    proto.RunStatus_RUN_FAILED,
+}
+
+// ScheduleOperation calls ScheduleOperation method of Service by handler
+func (b *EntityBalance) ScheduleOperation(ctx context.Context, req *proto.ScheduleOperationRequest) (*proto.ScheduleOperationResponse, error) {
+	schedule, err := b.parseSchedule(ctx, req.Schedule)
+	if err != nil {
+		return &proto.ScheduleOperationResponse{}, invalidArgument(fmt.Errorf("parseSchedule %w", err))
+	}
+	err = b.srvBalance.ScheduleOperation(ctx, schedule)
+	if err != nil {
+		return &proto.ScheduleOperationResponse{}, toStatus(fmt.Errorf("scheduleOperation %w", err))
+	}
+	return &proto.ScheduleOperationResponse{
+		Schedule: protoSchedule(schedule),
+	}, nil
+}
+
+// CancelSchedule calls CancelSchedule method of Service by handler
+func (b *EntityBalance) CancelSchedule(ctx context.Context, req *proto.CancelScheduleRequest) (*proto.CancelScheduleResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Scheduleid, "required,uuid")
+	if err != nil {
+		return &proto.CancelScheduleResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	scheduleUUID, err := uuid.Parse(req.Scheduleid)
+	if err != nil {
+		return &proto.CancelScheduleResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	schedule, err := b.srvBalance.CancelSchedule(ctx, scheduleUUID)
+	if err != nil {
+		return &proto.CancelScheduleResponse{}, toStatus(fmt.Errorf("cancelSchedule %w", err))
+	}
+	return &proto.CancelScheduleResponse{
+		Schedule: protoSchedule(schedule),
+	}, nil
+}
+
+// ListSchedules calls ListSchedules method of Service by handler
+func (b *EntityBalance) ListSchedules(ctx context.Context, req *proto.ListSchedulesRequest) (*proto.ListSchedulesResponse, error) {
+	err := b.validate.VarCtx(ctx, req.Profileid, "required,uuid")
+	if err != nil {
+		return &proto.ListSchedulesResponse{}, invalidArgument(fmt.Errorf("varCtx %w", err))
+	}
+	profileUUID, err := uuid.Parse(req.Profileid)
+	if err != nil {
+		return &proto.ListSchedulesResponse{}, invalidArgument(fmt.Errorf("parse %w", err))
+	}
+	schedules, err := b.srvBalance.ListSchedules(ctx, profileUUID)
+	if err != nil {
+		return &proto.ListSchedulesResponse{}, toStatus(fmt.Errorf("listSchedules %w", err))
+	}
+	protoSchedules := make([]*proto.Schedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		protoSchedules = append(protoSchedules, protoSchedule(schedule))
+	}
+	return &proto.ListSchedulesResponse{
+		Schedules: protoSchedules,
+	}, nil
+}
+
+// parseSchedule validates the schedule of request and converts it into model
+func (b *EntityBalance) parseSchedule(ctx context.Context, schedule *proto.Schedule) (*model.Schedule, error) {
+	err := b.validate.VarCtx(ctx, schedule, "required")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	err = b.validate.VarCtx(ctx, schedule.Profileid, "required,uuid")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	profileUUID, err := uuid.Parse(schedule.Profileid)
+	if err != nil {
+		return nil, fmt.Errorf("parse %w", err)
+	}
+	scheduleUUID, err := balanceIDOrNew(schedule.Scheduleid)
+	if err != nil {
+		return nil, fmt.Errorf("balanceIDOrNew %w", err)
+	}
+	currency := currencyOrDefault(schedule.Currency)
+	err = b.validate.VarCtx(ctx, currency, "iso4217")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	amount, err := decimal.NewFromString(schedule.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("newFromString %w", err)
+	}
+	err = checkPrecision(amount, currency)
+	if err != nil {
+		return nil, fmt.Errorf("checkPrecision %w", err)
+	}
+	operationType, err := parseOperationType(schedule.Type)
+	if err != nil {
+		return nil, fmt.Errorf("parseOperationType %w", err)
+	}
+	err = b.validate.VarCtx(ctx, schedule.Description, "max=256")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	err = b.validate.VarCtx(ctx, schedule.Expression, "required,max=128")
+	if err != nil {
+		return nil, fmt.Errorf("varCtx %w", err)
+	}
+	return &model.Schedule{
+		ScheduleID:    scheduleUUID,
+		ProfileID:     profileUUID,
+		Operation:     amount,
+		Currency:      currency,
+		OperationType: operationType,
+		Description:   schedule.Description,
+		Expression:    schedule.Expression,
+	}, nil
+}
+
+// protoSchedule converts schedule with its last run to response
+func protoSchedule(schedule *model.Schedule) *proto.Schedule {
+	protoSchedule := &proto.Schedule{
+		Scheduleid:  schedule.ScheduleID.String(),
+		Profileid:   schedule.ProfileID.String(),
+		Amount:      schedule.Operation.String(),
+		Currency:    schedule.Currency,
+		Type:        protoOperationTypes[schedule.OperationType],
+		Description: schedule.Description,
+		Expression:  schedule.Expression,
+		Status:      scheduleStatuses[schedule.Status],
+		Nextruntime: timestamppb.New(schedule.NextRunTime),
+		Createdtime: timestamppb.New(schedule.CreatedTime),
+	}
+	if schedule.LastRun != nil {
+		protoSchedule.Lastrun = &proto.ScheduleRun{
+			Runid:        schedule.LastRun.RunID.String(),
+			Runtime:      timestamppb.New(schedule.LastRun.RunTime),
+			Status:       runStatuses[schedule.LastRun.Status],
+			Errorcode:    schedule.LastRun.ErrorCode,
+			Reason:       schedule.LastRun.Reason,
+			Executedtime: timestamppb.New(schedule.LastRun.ExecutedTime),
+		}
+	}
+	return protoSchedule
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/handler/mocks"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestScheduleOperation(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	srv.On("ScheduleOperation", mock.Anything, mock.MatchedBy(func(schedule *model.Schedule) bool {
+		return schedule.ProfileID == testBalance.ProfileID && schedule.Operation.String() == "-9.99" && schedule.Expression == "@monthly"
+	})).Return(func(_ context.Context, schedule *model.Schedule) error {
+		schedule.Status = model.ScheduleActive
+		schedule.NextRunTime = time.Now().Add(time.Hour)
+		return nil
+	}).Once()
+	resp, err := hndl.ScheduleOperation(context.Background(), &proto.ScheduleOperationRequest{Schedule: &proto.Schedule{
+		Profileid:  testBalance.ProfileID.String(),
+		Amount:     "-9.99",
+		Type:       proto.OperationType_FEE,
+		Expression: "@monthly",
+	}})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Schedule.Scheduleid)
+	require.Equal(t, proto.ScheduleStatus_SCHEDULE_ACTIVE, resp.Schedule.Status)
+	require.Nil(t, resp.Schedule.Lastrun)
+	for _, schedule := range []*proto.Schedule{
+		{Profileid: testBalance.ProfileID.String(), Amount: "10"},
+		{Profileid: testBalance.ProfileID.String(), Amount: "many", Expression: "@daily"},
+		{Profileid: testBalance.ProfileID.String(), Amount: "10", Expression: "@daily", Type: proto.OperationType_TRANSFER},
+		nil,
+	} {
+		_, err = hndl.ScheduleOperation(context.Background(), &proto.ScheduleOperationRequest{Schedule: schedule})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	srv.AssertExpectations(t)
+}
+
+func TestListAndCancelSchedules(t *testing.T) {
+	srv := new(mocks.BalanceService)
+	hndl := NewEntityBalance(srv, v)
+	schedule := &model.Schedule{
+		ScheduleID: uuid.New(),
+		ProfileID:  testBalance.ProfileID,
+		Operation:  decimal.NewFromInt(-100),
+		Currency:   model.DefaultCurrency,
+		Expression: "@daily",
+		Status:     model.ScheduleActive,
+		LastRun:    &model.ScheduleRun{RunID: uuid.New(), Status: model.RunFailed, ErrorCode: berrors.NotEnoughMoney},
+	}
+	srv.On("ListSchedules", mock.Anything, testBalance.ProfileID).Return([]*model.Schedule{schedule}, nil).Once()
+	resp, err := hndl.ListSchedules(context.Background(), &proto.ListSchedulesRequest{Profileid: testBalance.ProfileID.String()})
+	require.NoError(t, err)
+	require.Len(t, resp.Schedules, 1)
+	require.Equal(t, proto.RunStatus_RUN_FAILED, resp.Schedules[0].Lastrun.Status)
+	require.Equal(t, berrors.NotEnoughMoney, resp.Schedules[0].Lastrun.Errorcode)
+
+	srv.On("CancelSchedule", mock.Anything, schedule.ScheduleID).Return(&model.Schedule{
+		ScheduleID: schedule.ScheduleID,
+		Status:     model.ScheduleCancelled,
+	}, nil).Once()
+	cancelled, err := hndl.CancelSchedule(context.Background(), &proto.CancelScheduleRequest{Scheduleid: schedule.ScheduleID.String()})
+	require.NoError(t, err)
+	require.Equal(t, proto.ScheduleStatus_SCHEDULE_CANCELLED, cancelled.Schedule.Status)
+	srv.On("CancelSchedule", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil, berrors.New(berrors.ScheduleNotFound)).Once()
+	_, err = hndl.CancelSchedule(context.Background(), &proto.CancelScheduleRequest{Scheduleid: uuid.NewString()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = hndl.CancelSchedule(context.Background(), &proto.CancelScheduleRequest{Scheduleid: "wrong"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	srv.AssertExpectations(t)
+}
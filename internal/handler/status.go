@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of ErrorInfo details which carry codes of business errors
+const ErrorDomain = "balance-service"
+
+// invalidArgument converts the error of request validation into status with codes.InvalidArgument
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// toStatus converts the error of service into status, so clients can tell business errors
+// from invalid requests and failures of database without matching messages. Messages of failures
+// of database aren`t sent to clients because they contain queries and names of constraints,
+// handlers log the whole error instead.
+func toStatus(err error) error {
+	var (
+		businessErr   *berrors.BusinessError
+		validationErr validator.ValidationErrors
+	)
+	switch {
+	case errors.As(err, &businessErr):
+		st := status.New(businessCode(businessErr.Code), businessErr.Error())
+		detailed, errDetails := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   businessErr.Code,
+			Domain:   ErrorDomain,
+			Metadata: businessErr.Metadata,
+		})
+		if errDetails != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	case errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case isUnavailable(err):
+		return status.Error(codes.Unavailable, "database is unavailable")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// businessCode returns status code for the code of business error, errors which aren`t caused
+// by the request itself or by existing data are reported as codes.FailedPrecondition
+func businessCode(code string) codes.Code {
+	switch code {
+	case berrors.InvalidAmount, berrors.ZeroAmount, berrors.SelfTransfer, berrors.CurrencyMismatch, berrors.InvalidOperationType,
+		berrors.InvalidSchedule:
+		return codes.InvalidArgument
+	case berrors.ProfileNotFound, berrors.HoldNotFound, berrors.OperationNotFound, berrors.ScheduleNotFound:
+		return codes.NotFound
+	case berrors.IdempotencyConflict, berrors.DuplicateOperation:
+		return codes.AlreadyExists
+	default:
+		return codes.FailedPrecondition
+	}
+}
+
+// isUnavailable reports whether the error is caused by lost or refused connection to database
+func isUnavailable(err error) bool {
+	var (
+		netErr net.Error
+		pgErr  *pgconn.PgError
+	)
+	switch {
+	case pgconn.SafeToRetry(err), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &pgErr):
+		// class 08 is connection exception and class 57P is operator intervention such as shutdown of server
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	default:
+		return false
+	}
+}
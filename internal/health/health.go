@@ -0,0 +1,98 @@
+// Package health reports whether service can serve requests by the standard gRPC health service and HTTP probes
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/logging"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Checker probes the database and sets status of service by the result, service isn`t serving until
+// the first successful probe
+type Checker struct {
+	server   *grpchealth.Server
+	ping     func(ctx context.Context) error
+	timeout  time.Duration
+	services []string
+	serving  atomic.Bool
+}
+
+// NewChecker accepts function which pings the database, timeout of ping and names of checked services
+// and returns an object of type *Checker. The overall health of server with empty name is always checked.
+func NewChecker(ping func(ctx context.Context) error, timeout time.Duration, services ...string) *Checker {
+	c := &Checker{
+		server:   grpchealth.NewServer(),
+		ping:     ping,
+		timeout:  timeout,
+		services: append([]string{""}, services...),
+	}
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Server returns the gRPC health service which reports status set by probes
+func (c *Checker) Server() healthpb.HealthServer {
+	return c.server
+}
+
+// Run probes the database at once and then periodically until ctx is done
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe pings the database and changes status of services if the database became reachable or unreachable,
+// every failed ping is logged
+func (c *Checker) probe(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	err := c.ping(pingCtx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("database is unreachable, service isn`t serving")
+		if c.serving.Swap(false) {
+			c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+		return
+	}
+	if !c.serving.Swap(true) {
+		logging.FromContext(ctx).Info("database is reachable, service is serving")
+		c.setStatus(healthpb.HealthCheckResponse_SERVING)
+	}
+}
+
+// setStatus sets status of all checked services
+func (c *Checker) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
+
+// Live handles liveness probe, it answers OK while process is able to handle requests
+func (c *Checker) Live(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(healthpb.HealthCheckResponse_SERVING.String()))
+}
+
+// Ready handles readiness probe, it answers OK only if the last probe of the database succeeded
+func (c *Checker) Ready(w http.ResponseWriter, _ *http.Request) {
+	if !c.serving.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(healthpb.HealthCheckResponse_NOT_SERVING.String()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(healthpb.HealthCheckResponse_SERVING.String()))
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const service = "BalanceService"
+
+// requireStatus checks status of service in gRPC health service and status code of readiness probe
+func requireStatus(t *testing.T, checker *Checker, status healthpb.HealthCheckResponse_ServingStatus, code int) {
+	for _, name := range []string{"", service} {
+		resp, err := checker.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: name})
+		require.NoError(t, err)
+		require.Equal(t, status, resp.Status)
+	}
+	recorder := httptest.NewRecorder()
+	checker.Ready(recorder, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+	require.Equal(t, code, recorder.Code)
+}
+
+func TestProbe(t *testing.T) {
+	var pingErr error
+	checker := NewChecker(func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return pingErr
+	}, time.Second, service)
+	requireStatus(t, checker, healthpb.HealthCheckResponse_NOT_SERVING, http.StatusServiceUnavailable)
+
+	checker.probe(context.Background())
+	requireStatus(t, checker, healthpb.HealthCheckResponse_SERVING, http.StatusOK)
+
+	pingErr = errors.New("connection refused")
+	checker.probe(context.Background())
+	requireStatus(t, checker, healthpb.HealthCheckResponse_NOT_SERVING, http.StatusServiceUnavailable)
+
+	pingErr = nil
+	checker.probe(context.Background())
+	requireStatus(t, checker, healthpb.HealthCheckResponse_SERVING, http.StatusOK)
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pinged := make(chan struct{})
+	checker := NewChecker(func(ctx context.Context) error {
+		close(pinged)
+		cancel()
+		return nil
+	}, time.Second)
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx, time.Hour)
+		close(done)
+	}()
+	<-pinged
+	<-done
+	resp, err := checker.Server().Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestLive(t *testing.T) {
+	checker := NewChecker(func(ctx context.Context) error { return nil }, time.Second)
+	recorder := httptest.NewRecorder()
+	checker.Live(recorder, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
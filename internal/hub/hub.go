@@ -0,0 +1,75 @@
+// Package hub delivers changes of balances to their watchers inside the process
+package hub
+
+import (
+	"sync"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+)
+
+// key identifies balance of profile in the currency
+type key struct {
+	profileID uuid.UUID
+	currency  string
+}
+
+// Hub contains watchers of balances, each watcher has a signal channel
+type Hub struct {
+	mu       sync.Mutex
+	watchers map[key]map[chan struct{}]struct{}
+}
+
+// New returns an empty hub
+func New() *Hub {
+	return &Hub{watchers: make(map[key]map[chan struct{}]struct{})}
+}
+
+// Subscribe returns channel which receives a signal after changes of balance and function which unsubscribes it.
+// Signals aren`t queued, so slow watcher gets one signal for several changes and should read the balance again.
+func (h *Hub) Subscribe(profileID uuid.UUID, currency string) (signals <-chan struct{}, unsubscribe func()) {
+	k := key{profileID: profileID, currency: currency}
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.watchers[k] == nil {
+		h.watchers[k] = make(map[chan struct{}]struct{})
+	}
+	h.watchers[k][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.watchers[k], ch)
+		if len(h.watchers[k]) == 0 {
+			delete(h.watchers, k)
+		}
+	}
+}
+
+// Publish signals watchers of the changed balance
+func (h *Hub) Publish(change *model.BalanceChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers[key{profileID: change.ProfileID, currency: change.Currency}] {
+		signal(ch)
+	}
+}
+
+// Broadcast signals all watchers, it is used when changes could be missed, for example after reconnect to database
+func (h *Hub) Broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, watchers := range h.watchers {
+		for ch := range watchers {
+			signal(ch)
+		}
+	}
+}
+
+// signal sends a signal without blocking, the pending signal already covers the new change
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
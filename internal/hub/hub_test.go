@@ -0,0 +1,38 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	h := New()
+	profileID := uuid.New()
+	signals, unsubscribe := h.Subscribe(profileID, model.DefaultCurrency)
+	other, unsubscribeOther := h.Subscribe(uuid.New(), model.DefaultCurrency)
+	defer unsubscribeOther()
+	h.Publish(&model.BalanceChange{ProfileID: profileID, Currency: model.DefaultCurrency})
+	h.Publish(&model.BalanceChange{ProfileID: profileID, Currency: model.DefaultCurrency})
+	h.Publish(&model.BalanceChange{ProfileID: profileID, Currency: "EUR"})
+	require.Len(t, signals, 1)
+	require.Empty(t, other)
+	<-signals
+	unsubscribe()
+	h.Publish(&model.BalanceChange{ProfileID: profileID, Currency: model.DefaultCurrency})
+	require.Empty(t, signals)
+	require.Len(t, h.watchers, 1)
+}
+
+func TestBroadcast(t *testing.T) {
+	h := New()
+	first, unsubscribeFirst := h.Subscribe(uuid.New(), model.DefaultCurrency)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := h.Subscribe(uuid.New(), "EUR")
+	defer unsubscribeSecond()
+	h.Broadcast()
+	require.Len(t, first, 1)
+	require.Len(t, second, 1)
+}
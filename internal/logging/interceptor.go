@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// RequestIDHeader is metadata key of request id, it is sent back to client in header of response
+	RequestIDHeader = "x-request-id"
+	// profileIDField is the name of fields of requests with id of profile
+	profileIDField = "profileid"
+)
+
+// UnaryServerInterceptor puts logger with request id, method and profile id of request into context of handler
+// and logs the end of every call with its status code and latency
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		requestID := RequestID(ctx)
+		// header can`t be sent only if there is no transport, for example when handler is called directly
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+		fields := logrus.Fields{RequestIDKey: requestID, MethodKey: info.FullMethod}
+		if profileID := profileIDOf(req); profileID != "" {
+			fields[ProfileIDKey] = profileID
+		}
+		logger := FromContext(ctx).WithFields(fields)
+		resp, err := handler(WithLogger(ctx, logger), req)
+		logEnd(logger, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor puts logger with request id and method into context of stream, profile id is added
+// when the first request is received, and logs the end of every stream with its status code and latency
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := ss.Context()
+		requestID := RequestID(ctx)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID))
+		logger := FromContext(ctx).WithFields(logrus.Fields{RequestIDKey: requestID, MethodKey: info.FullMethod})
+		stream := &serverStream{ServerStream: ss, ctx: WithLogger(ctx, logger), logger: logger}
+		err := handler(srv, stream)
+		logEnd(stream.logger, start, err)
+		return err
+	}
+}
+
+// serverStream gives handler context with logger, which gets profile id of the first received request
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	logger   *logrus.Entry
+	received bool
+}
+
+// Context returns context with logger of stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// RecvMsg receives request and adds its profile id to logger if it is the first one
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil || s.received {
+		return err
+	}
+	s.received = true
+	if profileID := profileIDOf(m); profileID != "" {
+		s.logger = s.logger.WithField(ProfileIDKey, profileID)
+		s.ctx = WithLogger(s.ctx, s.logger)
+	}
+	return nil
+}
+
+// logEnd logs the end of call with its status code and latency, the level depends on the code
+func logEnd(logger *logrus.Entry, start time.Time, err error) {
+	code := status.Code(err)
+	logger = logger.WithFields(logrus.Fields{
+		CodeKey:    code.String(),
+		LatencyKey: float64(time.Since(start)) / float64(time.Millisecond),
+	})
+	switch code {
+	case codes.OK:
+		logger.Info("request finished")
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		logger.WithError(err).Error("request failed")
+	default:
+		logger.WithError(err).Warn("request rejected")
+	}
+}
+
+// RequestID returns request id from incoming metadata or a new one if client didn`t send it
+func RequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return uuid.NewString()
+}
+
+// profileIDOf returns id of profile from the request or from its nested messages like balance of operation
+func profileIDOf(req any) string {
+	message, ok := req.(protoreflect.ProtoMessage)
+	if !ok {
+		return ""
+	}
+	return findProfileID(message.ProtoReflect(), 1)
+}
+
+// findProfileID looks for field with id of profile in the message and in its messages up to depth
+func findProfileID(message protoreflect.Message, depth int) string {
+	fields := message.Descriptor().Fields()
+	if field := fields.ByName(profileIDField); field != nil && field.Kind() == protoreflect.StringKind {
+		return message.Get(field).String()
+	}
+	if depth == 0 {
+		return ""
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Kind() != protoreflect.MessageKind || field.IsList() || field.IsMap() || !message.Has(field) {
+			continue
+		}
+		if profileID := findProfileID(message.Get(field).Message(), depth-1); profileID != "" {
+			return profileID
+		}
+	}
+	return ""
+}
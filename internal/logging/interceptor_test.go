@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artnikel/BalanceService/proto"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/BalanceService/BalanceOperation"}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	profileID := uuid.NewString()
+	ctx := metadata.NewIncomingContext(WithLogger(context.Background(), logrus.NewEntry(logger)),
+		metadata.Pairs(RequestIDHeader, "request-1"))
+	req := &proto.BalanceOperationRequest{Balance: &proto.Balance{Profileid: profileID, Amount: "10"}}
+	_, err := UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		FromContext(ctx).Info("handled")
+		return &proto.BalanceOperationResponse{}, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, hook.AllEntries(), 2)
+	for _, entry := range hook.AllEntries() {
+		require.Equal(t, "request-1", entry.Data[RequestIDKey])
+		require.Equal(t, info.FullMethod, entry.Data[MethodKey])
+		require.Equal(t, profileID, entry.Data[ProfileIDKey])
+	}
+	last := hook.LastEntry()
+	require.Equal(t, logrus.InfoLevel, last.Level)
+	require.Equal(t, codes.OK.String(), last.Data[CodeKey])
+	require.Contains(t, last.Data, LatencyKey)
+}
+
+func TestUnaryServerInterceptorFailure(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	ctx := WithLogger(context.Background(), logrus.NewEntry(logger))
+	_, err := UnaryServerInterceptor()(ctx, &proto.GetBalanceRequest{}, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.FailedPrecondition, "NOT_ENOUGH_MONEY")
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	last := hook.LastEntry()
+	require.Equal(t, logrus.WarnLevel, last.Level)
+	require.NotEmpty(t, last.Data[RequestIDKey])
+	require.NotContains(t, last.Data, ProfileIDKey)
+	require.Equal(t, codes.FailedPrecondition.String(), last.Data[CodeKey])
+
+	_, err = UnaryServerInterceptor()(ctx, &proto.GetBalanceRequest{}, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Internal, "connection refused")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+}
+
+// testStream is server stream which receives the request and remembers sent header
+type testStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	req    *proto.WatchBalanceRequest
+	header metadata.MD
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testStream) SetHeader(md metadata.MD) error {
+	s.header = md
+	return nil
+}
+
+func (s *testStream) RecvMsg(m any) error {
+	m.(*proto.WatchBalanceRequest).Profileid = s.req.Profileid
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	profileID := uuid.NewString()
+	stream := &testStream{
+		ctx: metadata.NewIncomingContext(WithLogger(context.Background(), logrus.NewEntry(logger)),
+			metadata.Pairs(RequestIDHeader, "request-1")),
+		req: &proto.WatchBalanceRequest{Profileid: profileID},
+	}
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/BalanceService/WatchBalance", IsServerStream: true}
+	err := StreamServerInterceptor()(nil, stream, streamInfo, func(srv any, ss grpc.ServerStream) error {
+		req := &proto.WatchBalanceRequest{}
+		require.NoError(t, ss.RecvMsg(req))
+		FromContext(ss.Context()).Info("handled")
+		return status.Error(codes.Canceled, "context canceled")
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+	require.Equal(t, []string{"request-1"}, stream.header.Get(RequestIDHeader))
+	require.Len(t, hook.AllEntries(), 2)
+	for _, entry := range hook.AllEntries() {
+		require.Equal(t, "request-1", entry.Data[RequestIDKey])
+		require.Equal(t, streamInfo.FullMethod, entry.Data[MethodKey])
+		require.Equal(t, profileID, entry.Data[ProfileIDKey])
+	}
+	last := hook.LastEntry()
+	require.Equal(t, logrus.WarnLevel, last.Level)
+	require.Equal(t, codes.Canceled.String(), last.Data[CodeKey])
+	require.Contains(t, last.Data, LatencyKey)
+}
+
+func TestConfigure(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+	err := Configure("debug")
+	require.NoError(t, err)
+	require.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	require.IsType(t, &logrus.JSONFormatter{}, logrus.StandardLogger().Formatter)
+	err = Configure("loud")
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+// Package logging configures JSON logs and carries logger with fields of request in context
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields which are used by all layers, so logs of one request can be found and filtered the same way
+const (
+	// RequestIDKey is field of id of request which is received in metadata or assigned by server
+	RequestIDKey = "request_id"
+	// MethodKey is field of full name of called RPC
+	MethodKey = "method"
+	// ProfileIDKey is field of id of profile
+	ProfileIDKey = "profile_id"
+	// OperationIDKey is field of id of operation
+	OperationIDKey = "operation_id"
+	// ScheduleIDKey is field of id of schedule
+	ScheduleIDKey = "schedule_id"
+	// CodeKey is field of status code of RPC or code of business error
+	CodeKey = "code"
+	// LatencyKey is field of duration of RPC in milliseconds
+	LatencyKey = "latency_ms"
+	// WorkerKey is field of name of background worker
+	WorkerKey = "worker"
+)
+
+// loggerKey is the key of logger in context
+type loggerKey struct{}
+
+// Configure makes the standard logger write JSON logs of level and above
+func Configure(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("parseLevel %w", err)
+	}
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetLevel(parsed)
+	return nil
+}
+
+// WithLogger returns context which carries the logger
+func WithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithFields returns context which carries logger of ctx with added fields
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext returns logger carried by context or the standard logger if context has no logger
+func FromContext(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
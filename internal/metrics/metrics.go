@@ -0,0 +1,50 @@
+// Package metrics exposes metrics of requests, business operations and database for Prometheus
+package metrics
+
+import (
+	"errors"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace is the prefix of names of all metrics of service
+const namespace = "balance"
+
+var (
+	operations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Number of recorded deposits and withdrawals.",
+	}, []string{"direction", "currency"})
+	volume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_volume_total",
+		Help:      "Sum of absolute amounts of recorded deposits and withdrawals.",
+	}, []string{"direction", "currency"})
+	rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejections_total",
+		Help:      "Number of operations rejected by business rules, such as NOT_ENOUGH_MONEY.",
+	}, []string{"code"})
+)
+
+// ObserveOperation counts the recorded operation and its amount, replays of recorded operations aren`t counted
+func ObserveOperation(balance *model.Balance) {
+	if balance.Replayed {
+		return
+	}
+	direction, currency := string(balance.Direction()), balance.Currency
+	operations.WithLabelValues(direction, currency).Inc()
+	volume.WithLabelValues(direction, currency).Add(balance.Operation.Abs().InexactFloat64())
+}
+
+// ObserveRejection counts the business error by its code, other errors aren`t counted
+func ObserveRejection(err error) {
+	var businessErr *berrors.BusinessError
+	if errors.As(err, &businessErr) {
+		rejections.WithLabelValues(businessErr.Code).Inc()
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestObserveOperation(t *testing.T) {
+	balance := &model.Balance{ProfileID: uuid.New(), Operation: decimal.NewFromFloat(-25.5), Currency: "EUR"}
+	direction := string(balance.Direction())
+	count := testutil.ToFloat64(operations.WithLabelValues(direction, "EUR"))
+	sum := testutil.ToFloat64(volume.WithLabelValues(direction, "EUR"))
+	ObserveOperation(balance)
+	require.Equal(t, count+1, testutil.ToFloat64(operations.WithLabelValues(direction, "EUR")))
+	require.Equal(t, sum+25.5, testutil.ToFloat64(volume.WithLabelValues(direction, "EUR")))
+
+	balance.Replayed = true
+	ObserveOperation(balance)
+	require.Equal(t, count+1, testutil.ToFloat64(operations.WithLabelValues(direction, "EUR")))
+}
+
+func TestObserveRejection(t *testing.T) {
+	count := testutil.ToFloat64(rejections.WithLabelValues(berrors.NotEnoughMoney))
+	ObserveRejection(berrors.New(berrors.NotEnoughMoney))
+	ObserveRejection(context.Canceled)
+	require.Equal(t, count+1, testutil.ToFloat64(rejections.WithLabelValues(berrors.NotEnoughMoney)))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/BalanceService/GetBalance"}
+	code := codes.NotFound.String()
+	count := testutil.ToFloat64(requests.WithLabelValues(info.FullMethod, code))
+	_, err := UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "OPERATION_NOT_FOUND")
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, count+1, testutil.ToFloat64(requests.WithLabelValues(info.FullMethod, code)))
+	require.Equal(t, 1, testutil.CollectAndCount(latency, "balance_grpc_request_duration_seconds"))
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/BalanceService/WatchBalance", IsServerStream: true}
+	code := codes.Canceled.String()
+	count := testutil.ToFloat64(requests.WithLabelValues(info.FullMethod, code))
+	err := StreamServerInterceptor()(nil, nil, info, func(srv any, ss grpc.ServerStream) error {
+		return status.Error(codes.Canceled, "context canceled")
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+	require.Equal(t, count+1, testutil.ToFloat64(requests.WithLabelValues(info.FullMethod, code)))
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector collects statistics of connection pool of database when metrics are scraped
+type PoolCollector struct {
+	pool             *pgxpool.Pool
+	acquired         *prometheus.Desc
+	idle             *prometheus.Desc
+	total            *prometheus.Desc
+	max              *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+}
+
+// NewPoolCollector accepts connection pool and returns an object of type *PoolCollector
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:             pool,
+		acquired:         desc("acquired_conns", "Number of connections which are used now."),
+		idle:             desc("idle_conns", "Number of idle connections."),
+		total:            desc("total_conns", "Number of connections in pool."),
+		max:              desc("max_conns", "Maximum size of pool."),
+		acquires:         desc("acquires_total", "Number of successful acquires of connections."),
+		emptyAcquires:    desc("empty_acquires_total", "Number of acquires which waited for connection because pool was empty."),
+		canceledAcquires: desc("canceled_acquires_total", "Number of acquires which were canceled by context."),
+		acquireDuration:  desc("acquire_wait_seconds_total", "Total time of waiting for connections."),
+	}
+}
+
+// Describe sends descriptions of statistics of pool
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{c.acquired, c.idle, c.total, c.max, c.acquires, c.emptyAcquires, c.canceledAcquires, c.acquireDuration} {
+		ch <- desc
+	}
+}
+
+// Collect sends the current statistics of pool
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountStatus is a state of profile which tells what operations it can make
+type AccountStatus string
+
+const (
+	// AccountActive allows all operations, profiles without recorded state are active
+	AccountActive AccountStatus = "active"
+	// AccountFrozen forbids withdrawals and deposits too if they are frozen
+	AccountFrozen AccountStatus = "frozen"
+	// AccountClosed forbids all operations and can`t be changed
+	AccountClosed AccountStatus = "closed"
+)
+
+// AccountState contains status of profile
+type AccountState struct {
+	ProfileID      uuid.UUID     `json:"profileid"`
+	Status         AccountStatus `json:"status"`
+	FreezeDeposits bool          `json:"freezedeposits"`
+	UpdatedTime    time.Time     `json:"updatedtime"`
+}
+
+// Allows reports whether the operation can be recorded for profile in this state
+func (s *AccountState) Allows(balance *Balance) bool {
+	switch s.Status {
+	case AccountClosed:
+		return false
+	case AccountFrozen:
+		return !s.FreezeDeposits && balance.Operation.IsPositive()
+	default:
+		return true
+	}
+}
+
+// AccountChange tells who changes state of profile and why
+type AccountChange struct {
+	ProfileID uuid.UUID `json:"profileid" validate:"required,uuid"`
+	Actor     string    `json:"actor" validate:"required,max=128"`
+	Reason    string    `json:"reason" validate:"required,max=1024"`
+}
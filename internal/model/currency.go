@@ -0,0 +1,21 @@
+package model
+
+// DefaultCurrency is used for operations and balances of clients which don`t send a currency
+const DefaultCurrency = "USD"
+
+// defaultMinorUnits is the number of decimal places of most ISO 4217 currencies
+const defaultMinorUnits = 2
+
+// MinorUnits returns the number of decimal places allowed for amounts in the ISO 4217 currency
+func MinorUnits(currency string) int32 {
+	switch currency {
+	case "BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG", "RWF", "UGX", "UYI", "VND", "VUV", "XAF", "XOF", "XPF":
+		return 0
+	case "BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND":
+		return 3
+	case "CLF", "UYW":
+		return 4
+	default:
+		return defaultMinorUnits
+	}
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AccountKind is a kind of ledger account
+type AccountKind string
+
+const (
+	// WalletAccount is an account of profile which holds its balance
+	WalletAccount AccountKind = "wallet"
+	// SettlementAccount is an account of the service which stands for money outside of it,
+	// deposits come from it and withdrawals go to it
+	SettlementAccount AccountKind = "settlement"
+	// FeeAccount is an account of the service which collects fees
+	FeeAccount AccountKind = "fee"
+)
+
+// SystemProfileID is the profile id of accounts which belong to the service itself
+var SystemProfileID = uuid.Nil
+
+// Account identifies ledger account by its kind, owner and currency
+type Account struct {
+	Kind      AccountKind `json:"kind"`
+	ProfileID uuid.UUID   `json:"profileid"`
+	Currency  string      `json:"currency"`
+}
+
+// Wallet returns the wallet account of profile in the currency
+func Wallet(profileID uuid.UUID, currency string) Account {
+	return Account{Kind: WalletAccount, ProfileID: profileID, Currency: currency}
+}
+
+// SystemAccount returns the account of the service of the kind in the currency
+func SystemAccount(kind AccountKind, currency string) Account {
+	return Account{Kind: kind, ProfileID: SystemProfileID, Currency: currency}
+}
+
+// Posting is a change of balance of one account in journal entry
+type Posting struct {
+	Account Account         `json:"account"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+// JournalEntry moves money between accounts, amounts of its postings sum to zero
+type JournalEntry struct {
+	EntryID  uuid.UUID  `json:"entryid"`
+	Postings []*Posting `json:"postings"`
+}
+
+// Entry returns the journal entry of operation, deposit moves money from settlement account to wallet of profile
+// and withdrawal moves it back, fees go to the fee account instead
+func (b *Balance) Entry() *JournalEntry {
+	kind := SettlementAccount
+	if b.OperationType == Fee {
+		kind = FeeAccount
+	}
+	return &JournalEntry{
+		EntryID: b.BalanceID,
+		Postings: []*Posting{
+			{Account: Wallet(b.ProfileID, b.Currency), Amount: b.Operation},
+			{Account: SystemAccount(kind, b.Currency), Amount: b.Operation.Neg()},
+		},
+	}
+}
+
+// Reverse returns the journal entry with id entryID which moves money of e back between the same accounts
+func (e *JournalEntry) Reverse(entryID uuid.UUID) *JournalEntry {
+	postings := make([]*Posting, 0, len(e.Postings))
+	for _, posting := range e.Postings {
+		postings = append(postings, &Posting{Account: posting.Account, Amount: posting.Amount.Neg()})
+	}
+	return &JournalEntry{EntryID: entryID, Postings: postings}
+}
+
+// Entry returns the journal entry of transfer which moves money between wallets of profiles,
+// it has the id of debit of transfer and covers both its debit and credit operations
+func (t *Transfer) Entry() *JournalEntry {
+	return &JournalEntry{
+		EntryID: t.Debit().BalanceID,
+		Postings: []*Posting{
+			{Account: Wallet(t.FromProfileID, t.Currency), Amount: t.Amount.Neg()},
+			{Account: Wallet(t.ToProfileID, t.Currency), Amount: t.Amount},
+		},
+	}
+}
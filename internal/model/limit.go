@@ -0,0 +1,83 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LimitDirection tells which operations are limited
+type LimitDirection string
+
+const (
+	// DepositLimit limits operations which add money
+	DepositLimit LimitDirection = "deposit"
+	// WithdrawalLimit limits operations which take money
+	WithdrawalLimit LimitDirection = "withdrawal"
+)
+
+// LimitPeriod is a rolling window in which operations are summed up
+type LimitPeriod string
+
+const (
+	// PerOperation limits the amount of a single operation
+	PerOperation LimitPeriod = "operation"
+	// Daily limits the sum of operations in the last day
+	Daily LimitPeriod = "day"
+	// Monthly limits the sum of operations in the last 30 days
+	Monthly LimitPeriod = "month"
+)
+
+// Window returns the duration of rolling window, it is zero for limit of a single operation
+func (p LimitPeriod) Window() time.Duration {
+	switch p {
+	case Daily:
+		return 24 * time.Hour
+	case Monthly:
+		return 30 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// DefaultLimitProfileID is the profile id of limits which apply to profiles without their own limits
+var DefaultLimitProfileID = uuid.Nil
+
+// Limit is the maximum amount of operations in currency which profile can make in the period
+type Limit struct {
+	ProfileID   uuid.UUID       `json:"profileid"`
+	Currency    string          `json:"currency" validate:"required,iso4217"`
+	Direction   LimitDirection  `json:"direction" validate:"required,oneof=deposit withdrawal"`
+	Period      LimitPeriod     `json:"period" validate:"required,oneof=operation day month"`
+	Amount      decimal.Decimal `json:"amount"`
+	UpdatedTime time.Time       `json:"updatedtime"`
+}
+
+// IsDefault reports whether the limit applies to all profiles without their own limit
+func (l *Limit) IsDefault() bool {
+	return l.ProfileID == DefaultLimitProfileID
+}
+
+// LimitUsage contains the limit and the sum of operations which are already made in its period
+type LimitUsage struct {
+	Limit *Limit          `json:"limit"`
+	Used  decimal.Decimal `json:"used"`
+}
+
+// Remaining returns the amount which can still be used in the period of limit
+func (u *LimitUsage) Remaining() decimal.Decimal {
+	remaining := u.Limit.Amount.Sub(u.Used)
+	if remaining.IsNegative() {
+		return decimal.Zero
+	}
+	return remaining
+}
+
+// Direction returns the direction of limits which apply to the operation
+func (b *Balance) Direction() LimitDirection {
+	if b.Operation.IsNegative() {
+		return WithdrawalLimit
+	}
+	return DepositLimit
+}
@@ -2,13 +2,256 @@
 package model
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// OperationType tells what the operation is for
+type OperationType string
+
+const (
+	// Deposit is money which came to profile from outside
+	Deposit OperationType = "deposit"
+	// Withdrawal is money which profile took out
+	Withdrawal OperationType = "withdrawal"
+	// TransferOperation is a debit or credit of transfer between profiles
+	TransferOperation OperationType = "transfer"
+	// TradeSettlement is the result of executed trade
+	TradeSettlement OperationType = "trade_settlement"
+	// Fee is money which the service charged
+	Fee OperationType = "fee"
+	// Refund is money which was returned to profile
+	Refund OperationType = "refund"
+	// Bonus is money which the service granted
+	Bonus OperationType = "bonus"
+	// HoldCapture is a withdrawal of captured hold
+	HoldCapture OperationType = "hold_capture"
+	// Reversal compensates another operation
+	Reversal OperationType = "reversal"
+)
+
+// DefaultOperationType returns the type of operation which has no type, it is a deposit or a withdrawal by its sign
+func DefaultOperationType(amount decimal.Decimal) OperationType {
+	if amount.IsNegative() {
+		return Withdrawal
+	}
+	return Deposit
+}
+
 // Balance contains an info about the balance and will be written in a balance table
 type Balance struct {
-	BalanceID uuid.UUID       `json:"balanceid" validate:"required,uuid"`
-	ProfileID uuid.UUID       `json:"profileid" validate:"required,uuid"`
-	Operation decimal.Decimal `json:"operation" validate:"required"`
+	BalanceID     uuid.UUID         `json:"balanceid" validate:"required,uuid"`
+	ProfileID     uuid.UUID         `json:"profileid" validate:"required,uuid"`
+	Operation     decimal.Decimal   `json:"operation" validate:"required"`
+	Currency      string            `json:"currency" validate:"required,iso4217"`
+	OperationTime time.Time         `json:"operationtime"`
+	TransferID    uuid.NullUUID     `json:"transferid"`
+	OperationType OperationType     `json:"operationtype"`
+	Description   string            `json:"description" validate:"max=256"`
+	Metadata      map[string]string `json:"metadata" validate:"max=32,dive,keys,required,max=64,endkeys,max=256"`
+	ReversalOf    uuid.NullUUID     `json:"reversalof"`
+	// Replayed is true if the operation was already recorded and its request was repeated
+	Replayed bool `json:"-"`
+}
+
+// Reverse returns the operation with id balanceID which compensates b and refers to it
+func (b *Balance) Reverse(balanceID uuid.UUID, description string) *Balance {
+	return &Balance{
+		BalanceID:     balanceID,
+		ProfileID:     b.ProfileID,
+		Operation:     b.Operation.Neg(),
+		Currency:      b.Currency,
+		OperationType: Reversal,
+		Description:   description,
+		ReversalOf:    uuid.NullUUID{UUID: b.BalanceID, Valid: true},
+	}
+}
+
+// Transfer contains an info about moving money from one profile to another,
+// it will be written in a balance table as two operations with the same transfer id
+type Transfer struct {
+	TransferID    uuid.UUID       `json:"transferid" validate:"required,uuid"`
+	FromProfileID uuid.UUID       `json:"fromprofileid" validate:"required,uuid"`
+	ToProfileID   uuid.UUID       `json:"toprofileid" validate:"required,uuid"`
+	Amount        decimal.Decimal `json:"amount" validate:"required"`
+	Currency      string          `json:"currency" validate:"required,iso4217"`
+}
+
+// Debit returns the operation which withdraws the amount of transfer from the source profile, its id is derived
+// from id of transfer, so ids of transfers don`t collide with ids of operations sent by clients
+func (t *Transfer) Debit() *Balance {
+	return &Balance{
+		BalanceID:     uuid.NewSHA1(t.TransferID, []byte("debit")),
+		ProfileID:     t.FromProfileID,
+		Operation:     t.Amount.Neg(),
+		Currency:      t.Currency,
+		TransferID:    uuid.NullUUID{UUID: t.TransferID, Valid: true},
+		OperationType: TransferOperation,
+	}
+}
+
+// Credit returns the operation which deposits the amount of transfer to the destination profile
+func (t *Transfer) Credit() *Balance {
+	return &Balance{
+		BalanceID:     uuid.NewSHA1(t.TransferID, []byte("credit")),
+		ProfileID:     t.ToProfileID,
+		Operation:     t.Amount,
+		Currency:      t.Currency,
+		TransferID:    uuid.NullUUID{UUID: t.TransferID, Valid: true},
+		OperationType: TransferOperation,
+	}
+}
+
+// OperationSign selects deposits, withdrawals or both in history of operations
+type OperationSign int
+
+const (
+	// AnySign selects both deposits and withdrawals
+	AnySign OperationSign = iota
+	// Deposits selects only operations with positive amount
+	Deposits
+	// Withdrawals selects only operations with negative amount
+	Withdrawals
+)
+
+// Cursor points to the last operation of the previous page of history
+type Cursor struct {
+	OperationTime time.Time `json:"operationtime"`
+	BalanceID     uuid.UUID `json:"balanceid"`
+}
+
+// OperationFilter contains conditions for reading history of operations of profile,
+// zero From and To mean that the time range isn`t limited from that side and empty Types mean operations of any type
+type OperationFilter struct {
+	ProfileID uuid.UUID
+	From      time.Time
+	To        time.Time
+	Sign      OperationSign
+	Types     []OperationType
+	Limit     int
+	After     *Cursor
+}
+
+// Drift is a difference between balance of wallet counted from its postings and its maintained balance
+type Drift struct {
+	ProfileID uuid.UUID       `json:"profileid"`
+	Currency  string          `json:"currency"`
+	Ledger    decimal.Decimal `json:"ledger"`
+	Snapshot  decimal.Decimal `json:"snapshot"`
+}
+
+// HoldStatus is a state of hold
+type HoldStatus string
+
+const (
+	// HoldActive means that amount of hold is reserved
+	HoldActive HoldStatus = "active"
+	// HoldCaptured means that amount of hold was withdrawn
+	HoldCaptured HoldStatus = "captured"
+	// HoldReleased means that amount of hold was returned to available balance
+	HoldReleased HoldStatus = "released"
+	// HoldExpired means that hold wasn`t captured before its expiration time and amount was returned
+	HoldExpired HoldStatus = "expired"
+)
+
+// Hold contains an info about money reserved before withdrawal and will be written in a hold table
+type Hold struct {
+	HoldID      uuid.UUID       `json:"holdid" validate:"required,uuid"`
+	ProfileID   uuid.UUID       `json:"profileid" validate:"required,uuid"`
+	Amount      decimal.Decimal `json:"amount" validate:"required"`
+	Currency    string          `json:"currency" validate:"required,iso4217"`
+	Captured    decimal.Decimal `json:"captured"`
+	Status      HoldStatus      `json:"status"`
+	ExpiresTime time.Time       `json:"expirestime"`
+}
+
+// Capture returns the operation which withdraws the captured amount of hold
+func (h *Hold) Capture() *Balance {
+	return &Balance{
+		BalanceID:     uuid.NewSHA1(h.HoldID, []byte("capture")),
+		ProfileID:     h.ProfileID,
+		Operation:     h.Captured.Neg(),
+		Currency:      h.Currency,
+		OperationType: HoldCapture,
+	}
+}
+
+// Reservation returns the withdrawal of the whole amount which hold reserves, it isn`t recorded
+// and is only checked when hold is made
+func (h *Hold) Reservation() *Balance {
+	return &Balance{
+		BalanceID:     uuid.NewSHA1(h.HoldID, []byte("capture")),
+		ProfileID:     h.ProfileID,
+		Operation:     h.Amount.Neg(),
+		Currency:      h.Currency,
+		OperationType: HoldCapture,
+	}
+}
+
+// Funds contains total balance of profile, the part of it which isn`t reserved by holds and credit limit of profile
+type Funds struct {
+	Total       decimal.Decimal `json:"total"`
+	Available   decimal.Decimal `json:"available"`
+	CreditLimit decimal.Decimal `json:"creditlimit"`
+}
+
+// Spendable returns the amount which profile can take, the balance may go negative down to minus credit limit
+func (f *Funds) Spendable() decimal.Decimal {
+	return f.Available.Add(f.CreditLimit)
+}
+
+// CreditLine is the credit limit of profile in currency
+type CreditLine struct {
+	ProfileID   uuid.UUID       `json:"profileid" validate:"required,uuid"`
+	Currency    string          `json:"currency" validate:"required,iso4217"`
+	CreditLimit decimal.Decimal `json:"creditlimit"`
+	UpdatedTime time.Time       `json:"updatedtime"`
+}
+
+// BatchOperation is an operation of batch with its journal entry and checks, Err is the reason why it wasn`t recorded
+type BatchOperation struct {
+	Balance *Balance
+	Entry   *JournalEntry
+	Checks  *Checks
+	Err     error
+}
+
+// Checks are business rules which are applied to state of profile read under its lock, nil check isn`t applied
+type Checks struct {
+	Funds   func(funds *Funds) error
+	Limits  func(usages []*LimitUsage) error
+	Account func(state *AccountState) error
+}
+
+// BalanceChange tells watchers that balance of profile in the currency was changed by committed transaction
+type BalanceChange struct {
+	ProfileID uuid.UUID `json:"profileid"`
+	Currency  string    `json:"currency"`
+}
+
+// OperationTopic is the topic of events about recorded operations
+const OperationTopic = "balance.operation"
+
+// EventStatus is a state of event in outbox
+type EventStatus string
+
+const (
+	// EventPending means that event wasn`t published yet
+	EventPending EventStatus = "pending"
+	// EventPublished means that event was accepted by broker
+	EventPublished EventStatus = "published"
+	// EventDead means that event wasn`t published after all attempts and was moved to dead letters
+	EventDead EventStatus = "dead"
+)
+
+// Event is a message about change of balance, it is written in outbox table with the change and published later
+type Event struct {
+	EventID     uuid.UUID       `json:"eventid"`
+	Topic       string          `json:"topic"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	CreatedTime time.Time       `json:"createdtime"`
 }
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ScheduleStatus tells whether operations of schedule are still made
+type ScheduleStatus string
+
+const (
+	// ScheduleActive is status of schedule whose operations are made when they are due
+	ScheduleActive ScheduleStatus = "active"
+	// ScheduleCancelled is status of schedule which makes no more operations
+	ScheduleCancelled ScheduleStatus = "cancelled"
+)
+
+// RunStatus is the result of run of schedule
+type RunStatus string
+
+const (
+	// RunSucceeded is status of run whose operation was recorded
+	RunSucceeded RunStatus = "succeeded"
+	// RunFailed is status of run whose operation was rejected, for example because of insufficient funds
+	RunFailed RunStatus = "failed"
+)
+
+// Schedule is an operation which is made repeatedly by cron expression like "0 9 1 * *" or by interval like "@every 24h"
+type Schedule struct {
+	ScheduleID    uuid.UUID       `json:"scheduleid"`
+	ProfileID     uuid.UUID       `json:"profileid"`
+	Operation     decimal.Decimal `json:"operation"`
+	Currency      string          `json:"currency"`
+	OperationType OperationType   `json:"operationtype"`
+	Description   string          `json:"description"`
+	Expression    string          `json:"expression"`
+	Status        ScheduleStatus  `json:"status"`
+	NextRunTime   time.Time       `json:"nextruntime"`
+	CreatedTime   time.Time       `json:"createdtime"`
+	LastRun       *ScheduleRun    `json:"lastrun,omitempty"`
+}
+
+// ScheduleRun is a record of operation made by schedule at its due time
+type ScheduleRun struct {
+	RunID        uuid.UUID `json:"runid"`
+	ScheduleID   uuid.UUID `json:"scheduleid"`
+	RunTime      time.Time `json:"runtime"`
+	Status       RunStatus `json:"status"`
+	ErrorCode    string    `json:"errorcode"`
+	Reason       string    `json:"reason"`
+	ExecutedTime time.Time `json:"executedtime"`
+}
+
+// RunID returns the id of the next run of schedule, it is the same for every attempt of the run,
+// so the operation of run is recorded only once
+func (s *Schedule) RunID() uuid.UUID {
+	return uuid.NewSHA1(s.ScheduleID, []byte(s.NextRunTime.UTC().Format(time.RFC3339Nano)))
+}
+
+// Balance returns the operation of the next run of schedule
+func (s *Schedule) Balance() *Balance {
+	return &Balance{
+		BalanceID:     s.RunID(),
+		ProfileID:     s.ProfileID,
+		Operation:     s.Operation,
+		Currency:      s.Currency,
+		OperationType: s.OperationType,
+		Description:   s.Description,
+		Metadata:      map[string]string{"scheduleid": s.ScheduleID.String()},
+	}
+}
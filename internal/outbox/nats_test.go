@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+)
+
+// natsMessage is a message received by stand-in of NATS server
+type natsMessage struct {
+	subject string
+	header  string
+	payload string
+}
+
+// serveNATS is a stand-in of NATS server with JetStream which accepts one connection and passes published messages
+// to the channel. Messages are acknowledged by stream if stored is true, otherwise no stream answers them.
+func serveNATS(t *testing.T, stored bool) (string, <-chan natsMessage) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+	messages := make(chan natsMessage, 10)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"stand-in\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+		reader := bufio.NewReader(conn)
+		var sid string
+		for seq := 1; ; {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 3 && fields[0] == "SUB":
+				sid = fields[2]
+			case len(fields) == 5 && fields[0] == "HPUB":
+				headerLen, _ := strconv.Atoi(fields[3])
+				totalLen, _ := strconv.Atoi(fields[4])
+				body := make([]byte, totalLen+2)
+				if _, err := io.ReadFull(reader, body); err != nil {
+					return
+				}
+				messages <- natsMessage{subject: fields[1], header: string(body[:headerLen]), payload: string(body[headerLen:totalLen])}
+				if !stored {
+					status := "NATS/1.0 503\r\n\r\n"
+					fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", fields[2], sid, len(status), len(status), status)
+					continue
+				}
+				ack := fmt.Sprintf(`{"stream":"BALANCE","seq":%d}`, seq)
+				seq++
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[2], sid, len(ack), ack)
+			case len(fields) == 1 && fields[0] == "PING":
+				fmt.Fprintf(conn, "PONG\r\n")
+			}
+		}
+	}()
+	return lis.Addr().String(), messages
+}
+
+func TestNATSPublisher(t *testing.T) {
+	address, messages := serveNATS(t, true)
+	publisher, err := NewNATSPublisher("nats://"+address, time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+	event := &model.Event{EventID: uuid.New(), Topic: model.OperationTopic, Payload: []byte(`{"operation":"100"}`)}
+	err = publisher.Publish(context.Background(), event)
+	require.NoError(t, err)
+	msg := <-messages
+	require.Equal(t, model.OperationTopic, msg.subject)
+	require.Contains(t, msg.header, jetstream.MsgIDHeader+": "+event.EventID.String())
+	require.Equal(t, `{"operation":"100"}`, msg.payload)
+}
+
+func TestNATSPublisherNotStored(t *testing.T) {
+	address, messages := serveNATS(t, false)
+	publisher, err := NewNATSPublisher(address, time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+	err = publisher.Publish(context.Background(), &model.Event{EventID: uuid.New(), Topic: model.OperationTopic, Payload: []byte(`{}`)})
+	require.ErrorIs(t, err, jetstream.ErrNoStreamResponse)
+	require.Equal(t, model.OperationTopic, (<-messages).subject)
+}
+
+func TestNATSPublisherUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	_, err = NewNATSPublisher("nats://"+address, time.Second)
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+// Package outbox publishes events written in outbox table to broker
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// claimLease is the time during which claimed event isn`t given to other relays
+	claimLease = time.Minute
+	// maxRetryDelay limits the growth of delay between attempts to publish event
+	maxRetryDelay = time.Hour
+)
+
+// Publisher is an interface of broker which accepts events
+type Publisher interface {
+	Publish(ctx context.Context, event *model.Event) error
+}
+
+// Repository is interface with methods of outbox table
+type Repository interface {
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]*model.Event, error)
+	MarkPublished(ctx context.Context, eventID uuid.UUID) error
+	RetryEvent(ctx context.Context, eventID uuid.UUID, reason string, delay time.Duration) error
+	DeadLetterEvent(ctx context.Context, eventID uuid.UUID, reason string) error
+}
+
+// Relay moves events from outbox to publisher, every event is published at least once
+// or moved to dead letters after maxAttempts failures
+type Relay struct {
+	rep         Repository
+	publisher   Publisher
+	batchSize   int
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewRelay accepts Repository and Publisher objects with settings of delivery and returns an object of type *Relay
+func NewRelay(rep Repository, publisher Publisher, batchSize, maxAttempts int, retryDelay time.Duration) *Relay {
+	return &Relay{rep: rep, publisher: publisher, batchSize: batchSize, maxAttempts: maxAttempts, retryDelay: retryDelay}
+}
+
+// PublishPending publishes one batch of due events and returns the number of claimed events,
+// failed events are retried later with growing delay
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	events, err := r.rep.ClaimEvents(ctx, r.batchSize, claimLease)
+	if err != nil {
+		return 0, fmt.Errorf("claimEvents %w", err)
+	}
+	for _, event := range events {
+		errPublish := r.publisher.Publish(ctx, event)
+		if errPublish == nil {
+			err = r.rep.MarkPublished(ctx, event.EventID)
+			if err != nil {
+				return len(events), fmt.Errorf("markPublished %w", err)
+			}
+			continue
+		}
+		if event.Attempts >= r.maxAttempts {
+			err = r.rep.DeadLetterEvent(ctx, event.EventID, errPublish.Error())
+			if err != nil {
+				return len(events), fmt.Errorf("deadLetterEvent %w", err)
+			}
+			continue
+		}
+		err = r.rep.RetryEvent(ctx, event.EventID, errPublish.Error(), r.delay(event.Attempts))
+		if err != nil {
+			return len(events), fmt.Errorf("retryEvent %w", err)
+		}
+	}
+	return len(events), nil
+}
+
+// delay returns the delay before the next attempt, it doubles after every failed attempt
+func (r *Relay) delay(attempts int) time.Duration {
+	delay := r.retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
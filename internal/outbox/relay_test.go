@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/outbox/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPublisher keeps published events in memory
+type memoryPublisher struct {
+	mu     sync.Mutex
+	events []*model.Event
+}
+
+func (m *memoryPublisher) Publish(_ context.Context, event *model.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(context.Context, *model.Event) error {
+	return errors.New("broker is unavailable")
+}
+
+func TestPublishPending(t *testing.T) {
+	rep := new(mocks.Repository)
+	publisher := &memoryPublisher{}
+	relay := NewRelay(rep, publisher, 10, 3, time.Second)
+	events := []*model.Event{
+		{EventID: uuid.New(), Topic: model.OperationTopic, Payload: []byte(`{"operation":"1"}`), Attempts: 1},
+		{EventID: uuid.New(), Topic: model.OperationTopic, Payload: []byte(`{"operation":"2"}`), Attempts: 1},
+	}
+	rep.On("ClaimEvents", mock.Anything, 10, claimLease).Return(events, nil).Once()
+	rep.On("MarkPublished", mock.Anything, events[0].EventID).Return(nil).Once()
+	rep.On("MarkPublished", mock.Anything, events[1].EventID).Return(nil).Once()
+	claimed, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, claimed)
+	require.Equal(t, events, publisher.events)
+	rep.AssertExpectations(t)
+}
+
+func TestPublishPendingFailures(t *testing.T) {
+	rep := new(mocks.Repository)
+	relay := NewRelay(rep, failingPublisher{}, 10, 3, time.Second)
+	retried := &model.Event{EventID: uuid.New(), Topic: model.OperationTopic, Attempts: 2}
+	dead := &model.Event{EventID: uuid.New(), Topic: model.OperationTopic, Attempts: 3}
+	rep.On("ClaimEvents", mock.Anything, 10, claimLease).Return([]*model.Event{retried, dead}, nil).Once()
+	rep.On("RetryEvent", mock.Anything, retried.EventID, "broker is unavailable", 2*time.Second).Return(nil).Once()
+	rep.On("DeadLetterEvent", mock.Anything, dead.EventID, "broker is unavailable").Return(nil).Once()
+	claimed, err := relay.PublishPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, claimed)
+	rep.AssertExpectations(t)
+	rep.AssertNotCalled(t, "MarkPublished", mock.Anything, mock.Anything)
+}
+
+func TestDelay(t *testing.T) {
+	relay := NewRelay(nil, nil, 10, 100, time.Second)
+	require.Equal(t, time.Second, relay.delay(1))
+	require.Equal(t, 4*time.Second, relay.delay(3))
+	require.Equal(t, maxRetryDelay, relay.delay(50))
+}
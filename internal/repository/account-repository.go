@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangeAccount locks the profile, applies the change of its state and records who changed it and why
+// in one transaction. State isn`t written and audited if apply doesn`t change it, error of apply is returned.
+// Unknown profile can`t be changed.
+func (p *PgRepository) ChangeAccount(ctx context.Context, change *model.AccountChange,
+	apply func(state *model.AccountState) error) (*model.AccountState, error) {
+	var state *model.AccountState
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := lockProfiles(ctx, tx, change.ProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		err = checkProfile(ctx, tx, change.ProfileID)
+		if err != nil {
+			return fmt.Errorf("checkProfile %w", err)
+		}
+		state, err = readAccount(ctx, tx, change.ProfileID)
+		if err != nil {
+			return fmt.Errorf("readAccount %w", err)
+		}
+		old := *state
+		err = apply(state)
+		if err != nil {
+			return err
+		}
+		if state.Status == old.Status && state.FreezeDeposits == old.FreezeDeposits {
+			return nil
+		}
+		err = tx.QueryRow(ctx, `INSERT INTO accounts (profileid, status, freezedeposits) VALUES ($1, $2, $3)
+			ON CONFLICT (profileid) DO UPDATE SET status = EXCLUDED.status, freezedeposits = EXCLUDED.freezedeposits, updatedtime = NOW()
+			RETURNING updatedtime`,
+			state.ProfileID, string(state.Status), state.FreezeDeposits).Scan(&state.UpdatedTime)
+		if err != nil {
+			return fmt.Errorf("queryRow %w", err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO account_audit (profileid, oldstatus, newstatus, freezedeposits, actor, reason)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			change.ProfileID, string(old.Status), string(state.Status), state.FreezeDeposits, change.Actor, change.Reason)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// readAccount returns state of profile, profile without recorded state is active
+func readAccount(ctx context.Context, q querier, profileID uuid.UUID) (*model.AccountState, error) {
+	var (
+		state  = &model.AccountState{ProfileID: profileID, Status: model.AccountActive}
+		status string
+	)
+	err := q.QueryRow(ctx, "SELECT status, freezedeposits, updatedtime FROM accounts WHERE profileid = $1", profileID).
+		Scan(&status, &state.FreezeDeposits, &state.UpdatedTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("queryRow %w", err)
+	}
+	state.Status = model.AccountStatus(status)
+	return state, nil
+}
+
+// checkProfile returns error if profile is unknown, that is it has neither wallet in any currency nor recorded state
+func checkProfile(ctx context.Context, q querier, profileID uuid.UUID) error {
+	var known bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ledger_accounts WHERE kind = $1 AND profileid = $2)
+		OR EXISTS (SELECT 1 FROM accounts WHERE profileid = $2)`, string(model.WalletAccount), profileID).Scan(&known)
+	if err != nil {
+		return fmt.Errorf("queryRow %w", err)
+	}
+	if !known {
+		return berrors.New(berrors.ProfileNotFound)
+	}
+	return nil
+}
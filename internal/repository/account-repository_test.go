@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeAccount(t *testing.T) {
+	change := &model.AccountChange{ProfileID: uuid.New(), Actor: "compliance", Reason: "investigation"}
+	freeze := func(state *model.AccountState) error {
+		state.Status = model.AccountFrozen
+		return nil
+	}
+	_, err := pg.ChangeAccount(context.Background(), change, freeze)
+	require.ErrorIs(t, err, berrors.New(berrors.ProfileNotFound))
+	deposit := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: change.ProfileID,
+		Operation: decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	}
+	err = balanceOperation(context.Background(), deposit, deposit.Entry())
+	require.NoError(t, err)
+	state, err := pg.ChangeAccount(context.Background(), change, freeze)
+	require.NoError(t, err)
+	require.Equal(t, model.AccountFrozen, state.Status)
+	_, err = pg.ChangeAccount(context.Background(), change, freeze)
+	require.NoError(t, err)
+	var audited int
+	err = pg.pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM account_audit WHERE profileid = $1 AND actor = $2",
+		change.ProfileID, change.Actor).Scan(&audited)
+	require.NoError(t, err)
+	require.Equal(t, 1, audited)
+
+	withdraw := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     change.ProfileID,
+		Operation:     decimal.NewFromInt(-1),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Withdrawal,
+	}
+	err = pg.CheckedBalanceOperation(context.Background(), withdraw, withdraw.Entry(), &model.Checks{
+		Account: func(state *model.AccountState) error {
+			if !state.Allows(withdraw) {
+				return berrors.New(berrors.AccountFrozen)
+			}
+			return nil
+		},
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+	_, err = pg.ChangeAccount(context.Background(), change, func(*model.AccountState) error {
+		return berrors.New(berrors.AccountClosed)
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountClosed))
+}
@@ -3,12 +3,20 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/logging"
 	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
 )
 
 // PgRepository represents the PostgreSQL repository implementation.
@@ -16,6 +24,15 @@ type PgRepository struct {
 	pool *pgxpool.Pool
 }
 
+// operationColumns are columns of balance table which are scanned by scanOperation
+const operationColumns = "balanceid, profileid, operation, currency, operationtime, transferid, operationtype, description, metadata, reversalof"
+
+// querier is implemented by both pool and transaction, so queries can be shared between them
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // NewPgRepository creates and returns a new instance of PgRepository, using the provided pgxpool.Pool.
 func NewPgRepository(pool *pgxpool.Pool) *PgRepository {
 	return &PgRepository{
@@ -23,35 +40,377 @@ func NewPgRepository(pool *pgxpool.Pool) *PgRepository {
 	}
 }
 
-// BalanceOperation allows to record a deposit or withdrawal transaction in the database
-func (p *PgRepository) BalanceOperation(ctx context.Context, balance *model.Balance) error {
-	_, err := p.pool.Exec(ctx, "INSERT INTO balance (balanceid, profileid, operation) VALUES ($1, $2, $3)",
-		balance.BalanceID, balance.ProfileID, balance.Operation)
+// CheckedBalanceOperation locks the profile, reads its state, available balance and usage of its limits in currency
+// of operation and records the operation in one transaction.
+// The operation is recorded only if checks accept state of profile, the available balance and usage of limits
+// in direction of operation, otherwise the error of failed check is returned.
+// Replay of already recorded operation with the same id does nothing and isn`t checked again.
+func (p *PgRepository) CheckedBalanceOperation(ctx context.Context, balance *model.Balance, entry *model.JournalEntry,
+	checks *model.Checks) error {
+	ctx, span := tracing.Start(ctx, "repository.CheckedBalanceOperation", tracing.Operation(balance)...)
+	defer span.End()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := lockProfiles(ctx, tx, balance.ProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		replayed, err := findReplay(ctx, tx, balance)
+		if err != nil || replayed {
+			return err
+		}
+		err = applyChecks(ctx, tx, balance, checks)
+		if err != nil {
+			return err
+		}
+		return insertOperation(ctx, tx, balance, entry)
+	})
+}
+
+// applyChecks reads only the state which is needed by checks of operation and applies them
+func applyChecks(ctx context.Context, tx pgx.Tx, balance *model.Balance, checks *model.Checks) error {
+	ctx, span := tracing.Start(ctx, "repository.applyChecks")
+	defer span.End()
+	if checks.Account != nil {
+		state, err := readAccount(ctx, tx, balance.ProfileID)
+		if err != nil {
+			return fmt.Errorf("readAccount %w", err)
+		}
+		err = checks.Account(state)
+		if err != nil {
+			return err
+		}
+	}
+	if checks.Funds != nil {
+		funds, err := readFunds(ctx, tx, balance.ProfileID, balance.Currency)
+		if err != nil {
+			return fmt.Errorf("readFunds %w", err)
+		}
+		err = checks.Funds(funds)
+		if err != nil {
+			return err
+		}
+	}
+	if checks.Limits != nil {
+		usages, err := readLimitUsages(ctx, tx, balance)
+		if err != nil {
+			return fmt.Errorf("readLimitUsages %w", err)
+		}
+		err = checks.Limits(usages)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transfer locks both profiles in a fixed order, so opposite transfers can`t deadlock, and records
+// debit and credit operations of transfer with its journal entry in one transaction if checks of each operation
+// accept state of its profile. Replay of already recorded transfer with the same id does nothing
+// and isn`t checked again.
+func (p *PgRepository) Transfer(ctx context.Context, transfer *model.Transfer, entry *model.JournalEntry,
+	checks func(operation *model.Balance) *model.Checks) error {
+	ctx, span := tracing.Start(ctx, "repository.Transfer", tracing.ProfileIDKey.String(transfer.FromProfileID.String()))
+	defer span.End()
+	debit, credit := transfer.Debit(), transfer.Credit()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := lockProfiles(ctx, tx, transfer.FromProfileID, transfer.ToProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		replayedDebit, err := findReplay(ctx, tx, debit)
+		if err != nil {
+			return err
+		}
+		replayedCredit, err := findReplay(ctx, tx, credit)
+		if err != nil || (replayedDebit && replayedCredit) {
+			return err
+		}
+		for _, operation := range []*model.Balance{debit, credit} {
+			err = applyChecks(ctx, tx, operation, checks(operation))
+			if err != nil {
+				return err
+			}
+		}
+		err = insertOperation(ctx, tx, debit, entry)
+		if err != nil {
+			return err
+		}
+		// postings of credit are already recorded in the entry of transfer
+		return insertOperation(ctx, tx, credit, nil)
+	})
+}
+
+// GetBalance returns total balance of profile by him id in the currency from its wallet account
+// and the available part of it which isn`t reserved by holds, balance of unknown profile isn`t found
+func (p *PgRepository) GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetBalance", tracing.ProfileIDKey.String(profileID.String()))
+	defer span.End()
+	err := checkProfile(ctx, p.pool, profileID)
 	if err != nil {
-		return fmt.Errorf("exec %w", err)
+		return nil, fmt.Errorf("checkProfile %w", err)
 	}
+	return readFunds(ctx, p.pool, profileID, currency)
+}
 
-	return nil
+// GetBalanceAt returns total balance of profile in currency as the sum of its operations recorded until asOf,
+// the sum is read by index of operations of profile by time. Holds and credit line aren`t kept in history,
+// so only total balance is known. Balance of unknown profile isn`t found.
+func (p *PgRepository) GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error) {
+	ctx, span := tracing.Start(ctx, "repository.GetBalanceAt", tracing.ProfileIDKey.String(profileID.String()))
+	defer span.End()
+	err := checkProfile(ctx, p.pool, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("checkProfile %w", err)
+	}
+	var funds model.Funds
+	err = p.pool.QueryRow(ctx, `SELECT COALESCE(SUM(operation), 0) FROM balance
+		WHERE profileid = $1 AND currency = $2 AND operationtime <= $3`, profileID, currency, asOf.UTC()).Scan(&funds.Total)
+	if err != nil {
+		return nil, fmt.Errorf("queryRow %w", err)
+	}
+	return &funds, nil
 }
 
-// GetBalance counted sum of operations and returns balance of profile by him id
-func (p *PgRepository) GetBalance(ctx context.Context, profileID uuid.UUID) (float64, error) {
-	rows, err := p.pool.Query(ctx, "SELECT operation FROM balance WHERE profileid = $1 FOR UPDATE", profileID)
+// Reconcile counts balances of wallets from postings again and returns the ones which differ from maintained balances
+func (p *PgRepository) Reconcile(ctx context.Context) ([]*model.Drift, error) {
+	rows, err := p.pool.Query(ctx, `SELECT a.profileid, a.currency, COALESCE(l.money, 0), a.money
+		FROM ledger_accounts a
+		LEFT JOIN (SELECT accountid, SUM(amount) AS money FROM postings GROUP BY accountid) l ON l.accountid = a.accountid
+		WHERE a.kind = $1 AND COALESCE(l.money, 0) <> a.money`, string(model.WalletAccount))
 	if err != nil {
-		return 0, fmt.Errorf("query %w", err)
+		return nil, fmt.Errorf("query %w", err)
 	}
 	defer rows.Close()
 
-	var money decimal.Decimal
+	var drifts []*model.Drift
 
 	for rows.Next() {
-		var operation decimal.Decimal
-		err := rows.Scan(&operation)
+		drift := &model.Drift{}
+		err := rows.Scan(&drift.ProfileID, &drift.Currency, &drift.Ledger, &drift.Snapshot)
 		if err != nil {
-			return 0, fmt.Errorf("scan %w", err)
+			return nil, fmt.Errorf("scan %w", err)
 		}
-		money = money.Add(operation)
+		drifts = append(drifts, drift)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+
+	return drifts, nil
+}
+
+// GetOperation returns the recorded operation by its id
+func (p *PgRepository) GetOperation(ctx context.Context, balanceID uuid.UUID) (*model.Balance, error) {
+	operation, err := scanOperation(p.pool.QueryRow(ctx, "SELECT "+operationColumns+" FROM balance WHERE balanceid = $1", balanceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, berrors.New(berrors.OperationNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanOperation %w", err)
 	}
+	return operation, nil
+}
 
-	return money.InexactFloat64(), nil
+// ReverseOperation records the reversal with its journal entry if the reversed operation wasn`t reversed yet
+// and checks accept state of profile. Replay of already recorded reversal with the same id does nothing.
+func (p *PgRepository) ReverseOperation(ctx context.Context, reversal *model.Balance, entry *model.JournalEntry,
+	checks *model.Checks) error {
+	ctx, span := tracing.Start(ctx, "repository.ReverseOperation", tracing.Operation(reversal)...)
+	defer span.End()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := lockProfiles(ctx, tx, reversal.ProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		replayed, err := findReplay(ctx, tx, reversal)
+		if err != nil || replayed {
+			return err
+		}
+		var reversalID uuid.UUID
+		err = tx.QueryRow(ctx, "SELECT balanceid FROM balance WHERE reversalof = $1", reversal.ReversalOf.UUID).Scan(&reversalID)
+		if err == nil {
+			return berrors.New(berrors.DuplicateOperation)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("queryRow %w", err)
+		}
+		err = applyChecks(ctx, tx, reversal, checks)
+		if err != nil {
+			return err
+		}
+		return insertOperation(ctx, tx, reversal, entry)
+	})
+}
+
+// ListOperations returns operations of profile which match the filter ordered by operation time,
+// the page starts after the cursor of filter
+func (p *PgRepository) ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, error) {
+	query := "SELECT " + operationColumns + " FROM balance WHERE profileid = $1"
+	args := []any{filter.ProfileID}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		query += fmt.Sprintf(" AND operationtime >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		query += fmt.Sprintf(" AND operationtime < $%d", len(args))
+	}
+	switch filter.Sign {
+	case model.Deposits:
+		query += " AND operation > 0"
+	case model.Withdrawals:
+		query += " AND operation < 0"
+	case model.AnySign:
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, 0, len(filter.Types))
+		for _, operationType := range filter.Types {
+			types = append(types, string(operationType))
+		}
+		args = append(args, types)
+		query += fmt.Sprintf(" AND operationtype = ANY($%d)", len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.OperationTime.UTC(), filter.After.BalanceID)
+		query += fmt.Sprintf(" AND (operationtime, balanceid) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY operationtime, balanceid LIMIT $%d", len(args))
+
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	var operations []*model.Balance
+
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanOperation %w", err)
+		}
+		operations = append(operations, operation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+
+	return operations, nil
+}
+
+// readFunds returns the maintained balance of wallet of profile in the currency, the part of it which isn`t reserved
+// by active holds and credit limit of profile, profile without operations has zero balance
+func readFunds(ctx context.Context, q querier, profileID uuid.UUID, currency string) (*model.Funds, error) {
+	var (
+		funds model.Funds
+		held  decimal.Decimal
+	)
+	err := q.QueryRow(ctx, `SELECT
+		COALESCE((SELECT money FROM ledger_accounts WHERE kind = $4 AND profileid = $1 AND currency = $2), 0),
+		COALESCE((SELECT SUM(amount) FROM hold WHERE profileid = $1 AND currency = $2 AND status = $3 AND expirestime > NOW()), 0),
+		COALESCE((SELECT creditlimit FROM credit_line WHERE profileid = $1 AND currency = $2), 0)`,
+		profileID, currency, string(model.HoldActive), string(model.WalletAccount)).Scan(&funds.Total, &held, &funds.CreditLimit)
+	if err != nil {
+		return nil, fmt.Errorf("queryRow %w", err)
+	}
+	funds.Available = funds.Total.Sub(held)
+	return &funds, nil
+}
+
+// lockProfiles takes transaction level locks of profiles in order of their ids
+func lockProfiles(ctx context.Context, tx pgx.Tx, profileIDs ...uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "repository.lockProfiles")
+	defer span.End()
+	keys := make([]string, 0, len(profileIDs))
+	for _, profileID := range profileIDs {
+		keys = append(keys, profileID.String())
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", key)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+	}
+	return nil
+}
+
+// insertOperation records the operation with its journal entry and its event in outbox and notifies watchers of balance,
+// entry is nil if postings of operation are recorded by entry of another operation.
+// Replay of already recorded operation with the same id does nothing.
+func insertOperation(ctx context.Context, tx pgx.Tx, balance *model.Balance, entry *model.JournalEntry) error {
+	ctx, span := tracing.Start(ctx, "repository.insertOperation", tracing.Operation(balance)...)
+	defer span.End()
+	metadata := balance.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	err := tx.QueryRow(ctx, `INSERT INTO balance (balanceid, profileid, operation, currency, transferid, operationtype, description,
+		metadata, reversalof) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (balanceid) DO NOTHING RETURNING operationtime`,
+		balance.BalanceID, balance.ProfileID, balance.Operation, balance.Currency, balance.TransferID,
+		string(balance.OperationType), balance.Description, metadata, balance.ReversalOf).Scan(&balance.OperationTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_, err = findReplay(ctx, tx, balance)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("queryRow %w", err)
+	}
+	if entry != nil {
+		err = insertEntry(ctx, tx, entry)
+		if err != nil {
+			return fmt.Errorf("insertEntry %w", err)
+		}
+	}
+	err = insertEvent(ctx, tx, model.OperationTopic, balance)
+	if err != nil {
+		return fmt.Errorf("insertEvent %w", err)
+	}
+	return notifyChange(ctx, tx, balance.ProfileID, balance.Currency)
+}
+
+// findReplay looks for already recorded operation with the same id. It returns true and sets time of operation
+// if such operation has the same payload and the business error if the id was used for another operation.
+func findReplay(ctx context.Context, q querier, balance *model.Balance) (bool, error) {
+	recorded, err := scanOperation(q.QueryRow(ctx, "SELECT "+operationColumns+" FROM balance WHERE balanceid = $1", balance.BalanceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("scanOperation %w", err)
+	}
+	return replayOf(ctx, recorded, balance)
+}
+
+// replayOf reports whether the operation replays the recorded one and sets time of operation,
+// it returns the business error if the id was used for another operation
+func replayOf(ctx context.Context, recorded, balance *model.Balance) (bool, error) {
+	logger := logging.FromContext(ctx).WithFields(logrus.Fields{
+		logging.OperationIDKey: balance.BalanceID,
+		logging.ProfileIDKey:   balance.ProfileID,
+	})
+	if recorded.ProfileID != balance.ProfileID || !recorded.Operation.Equal(balance.Operation) || recorded.Currency != balance.Currency ||
+		recorded.OperationType != balance.OperationType || recorded.ReversalOf != balance.ReversalOf {
+		logger.Warn("id of recorded operation is used for another operation")
+		return false, berrors.New(berrors.IdempotencyConflict)
+	}
+	logger.Debug("replay of recorded operation")
+	balance.OperationTime, balance.Replayed = recorded.OperationTime, true
+	return true, nil
+}
+
+// scanOperation scans operationColumns of the row into operation
+func scanOperation(row pgx.Row) (*model.Balance, error) {
+	var (
+		operation     = &model.Balance{}
+		operationType string
+	)
+	err := row.Scan(&operation.BalanceID, &operation.ProfileID, &operation.Operation, &operation.Currency, &operation.OperationTime,
+		&operation.TransferID, &operationType, &operation.Description, &operation.Metadata, &operation.ReversalOf)
+	if err != nil {
+		return nil, err
+	}
+	operation.OperationType = model.OperationType(operationType)
+	return operation, nil
 }
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sync"
 	"testing"
+	"time"
 
+	berrors "github.com/artnikel/BalanceService/internal/errors"
 	"github.com/artnikel/BalanceService/internal/model"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest"
 	"github.com/shopspring/decimal"
@@ -21,6 +25,7 @@ var (
 		BalanceID: uuid.New(),
 		ProfileID: uuid.New(),
 		Operation: decimal.NewFromFloat(100.9),
+		Currency:  model.DefaultCurrency,
 	}
 )
 
@@ -71,6 +76,13 @@ func RunMigrations(port string) error {
 	return nil
 }
 
+// balanceOperation records the operation with its journal entry without locking the profile and checks of its state
+func balanceOperation(ctx context.Context, balance *model.Balance, entry *model.JournalEntry) error {
+	return pgx.BeginFunc(ctx, pg.pool, func(tx pgx.Tx) error {
+		return insertOperation(ctx, tx, balance, entry)
+	})
+}
+
 func TestMain(m *testing.M) {
 	dbpool, cleanupPostgres, err := SetupTestPostgres()
 	if err != nil {
@@ -85,35 +97,505 @@ func TestMain(m *testing.M) {
 }
 
 func TestOperationWithGetBalance(t *testing.T) {
-	err := pg.BalanceOperation(context.Background(), testBalance)
+	err := balanceOperation(context.Background(), testBalance, testBalance.Entry())
 	require.NoError(t, err)
-	money, err := pg.GetBalance(context.Background(), testBalance.ProfileID)
+	funds, err := pg.GetBalance(context.Background(), testBalance.ProfileID, model.DefaultCurrency)
 	require.NoError(t, err)
-	require.Equal(t, money, testBalance.Operation.InexactFloat64())
+	require.True(t, funds.Total.Equal(testBalance.Operation))
 }
 
 func TestBalanceOperations(t *testing.T) {
 	testBalance.ProfileID = uuid.New()
 	testBalance.BalanceID = uuid.New()
 	testBalance.Operation = decimal.NewFromFloat(800.5)
-	err := pg.BalanceOperation(context.Background(), testBalance)
+	err := balanceOperation(context.Background(), testBalance, testBalance.Entry())
 	require.NoError(t, err)
 	testBalance.Operation = decimal.NewFromFloat(-700.5)
 	testBalance.BalanceID = uuid.New()
-	err = pg.BalanceOperation(context.Background(), testBalance)
+	err = balanceOperation(context.Background(), testBalance, testBalance.Entry())
 	require.NoError(t, err)
-	money, err := pg.GetBalance(context.Background(), testBalance.ProfileID)
+	funds, err := pg.GetBalance(context.Background(), testBalance.ProfileID, model.DefaultCurrency)
 	require.NoError(t, err)
-	require.Equal(t, decimal.NewFromFloat(money), decimal.NewFromFloat(100.0))
+	require.True(t, funds.Total.Equal(decimal.NewFromFloat(100.0)))
 }
 
 func TestGetBalanceByFakeID(t *testing.T) {
-	money, _ := pg.GetBalance(context.Background(), uuid.Nil)
-	require.Empty(t, money)
-	money, _ = pg.GetBalance(context.Background(), uuid.New())
-	require.Empty(t, money)
+	_, err := pg.GetBalance(context.Background(), uuid.Nil, model.DefaultCurrency)
+	require.ErrorIs(t, err, berrors.New(berrors.ProfileNotFound))
+	_, err = pg.GetBalance(context.Background(), uuid.New(), model.DefaultCurrency)
+	require.ErrorIs(t, err, berrors.New(berrors.ProfileNotFound))
 	fakeUUID, err := uuid.Parse("00000000-0000-0000-0000-41db8a3d9113")
 	require.NoError(t, err)
-	money, _ = pg.GetBalance(context.Background(), fakeUUID)
-	require.Empty(t, money)
+	_, err = pg.GetBalance(context.Background(), fakeUUID, model.DefaultCurrency)
+	require.ErrorIs(t, err, berrors.New(berrors.ProfileNotFound))
+	_, err = pg.GetBalanceAt(context.Background(), fakeUUID, model.DefaultCurrency, time.Now())
+	require.ErrorIs(t, err, berrors.New(berrors.ProfileNotFound))
+	funds, err := pg.GetBalance(context.Background(), testBalance.ProfileID, "EUR")
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+}
+
+func TestParallelWithdrawals(t *testing.T) {
+	const withdrawals = 20
+	profileID := uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: profileID,
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	amount := decimal.NewFromInt(10)
+	check := func(funds *model.Funds) error {
+		if funds.Available.LessThan(amount) {
+			return berrors.New(berrors.NotEnoughMoney)
+		}
+		return nil
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []error
+	)
+	for i := 0; i < withdrawals; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			operation := &model.Balance{
+				BalanceID: uuid.New(),
+				ProfileID: profileID,
+				Operation: amount.Neg(),
+				Currency:  model.DefaultCurrency,
+			}
+			errWithdraw := pg.CheckedBalanceOperation(context.Background(), operation, operation.Entry(), &model.Checks{Funds: check})
+			if errWithdraw != nil {
+				mu.Lock()
+				failed = append(failed, errWithdraw)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, failed, withdrawals/2)
+	for _, errWithdraw := range failed {
+		var e *berrors.BusinessError
+		require.ErrorAs(t, errWithdraw, &e)
+	}
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+}
+
+func TestExactBalance(t *testing.T) {
+	profileID := uuid.New()
+	for _, amount := range []string{"0.1", "0.2", "1234567890.12"} {
+		operation := &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: profileID,
+			Operation: decimal.RequireFromString(amount),
+			Currency:  model.DefaultCurrency,
+		}
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "1234567890.42", funds.Total.String())
+}
+
+func TestBalancePerCurrency(t *testing.T) {
+	profileID := uuid.New()
+	for currency, amount := range map[string]string{"USD": "10.5", "EUR": "20.25", "JPY": "300"} {
+		operation := &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: profileID,
+			Operation: decimal.RequireFromString(amount),
+			Currency:  currency,
+		}
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	funds, err := pg.GetBalance(context.Background(), profileID, "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "20.25", funds.Total.String())
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: profileID,
+		Operation: decimal.NewFromInt(-300),
+		Currency:  "JPY",
+	}
+	err = pg.CheckedBalanceOperation(context.Background(), operation, operation.Entry(), &model.Checks{Funds: func(funds *model.Funds) error {
+		require.Equal(t, "300", funds.Available.String())
+		return nil
+	}})
+	require.NoError(t, err)
+	funds, err = pg.GetBalance(context.Background(), profileID, "JPY")
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	profileID := uuid.New()
+	operations := make([]*model.Balance, 0, 3)
+	for _, amount := range []int64{100, -30, 50} {
+		operation := &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: profileID,
+			Operation: decimal.NewFromInt(amount),
+			Currency:  model.DefaultCurrency,
+		}
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+		operations = append(operations, operation)
+		time.Sleep(10 * time.Millisecond)
+	}
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: profileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(20),
+		Currency:      model.DefaultCurrency,
+	}
+	err := pg.Transfer(context.Background(), transfer, transfer.Entry(), noChecks)
+	require.NoError(t, err)
+	debit, err := pg.GetOperation(context.Background(), transfer.Debit().BalanceID)
+	require.NoError(t, err)
+	between := operations[0].OperationTime.Add(operations[1].OperationTime.Sub(operations[0].OperationTime) / 2)
+	for asOf, total := range map[time.Time]string{
+		operations[0].OperationTime.Add(-time.Microsecond): "0",
+		operations[0].OperationTime:                        "100",
+		between:                                            "100",
+		operations[1].OperationTime:                        "70",
+		operations[2].OperationTime.Add(-time.Microsecond): "70",
+		operations[2].OperationTime:                        "120",
+		debit.OperationTime:                                "100",
+	} {
+		funds, err := pg.GetBalanceAt(context.Background(), profileID, model.DefaultCurrency, asOf)
+		require.NoError(t, err)
+		require.Equal(t, total, funds.Total.String())
+	}
+}
+
+func TestReplayBalanceOperation(t *testing.T) {
+	deposit := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(50),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), deposit, deposit.Entry())
+	require.NoError(t, err)
+	err = balanceOperation(context.Background(), deposit, deposit.Entry())
+	require.NoError(t, err)
+	withdraw := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: deposit.ProfileID,
+		Operation: decimal.NewFromInt(-50),
+		Currency:  model.DefaultCurrency,
+	}
+	checks := 0
+	check := func(funds *model.Funds) error {
+		checks++
+		if funds.Available.LessThan(withdraw.Operation.Abs()) {
+			return berrors.New(berrors.NotEnoughMoney)
+		}
+		return nil
+	}
+	err = pg.CheckedBalanceOperation(context.Background(), withdraw, withdraw.Entry(), &model.Checks{Funds: check})
+	require.NoError(t, err)
+	err = pg.CheckedBalanceOperation(context.Background(), withdraw, withdraw.Entry(), &model.Checks{Funds: check})
+	require.NoError(t, err)
+	require.Equal(t, 1, checks)
+	funds, err := pg.GetBalance(context.Background(), deposit.ProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+}
+
+func TestConflictingReplayBalanceOperation(t *testing.T) {
+	deposit := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(50),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), deposit, deposit.Entry())
+	require.NoError(t, err)
+	conflicting := *deposit
+	conflicting.Operation = decimal.NewFromInt(60)
+	err = balanceOperation(context.Background(), &conflicting, conflicting.Entry())
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.IdempotencyConflict, e.Code)
+	conflicting.Operation = decimal.NewFromInt(-50)
+	err = pg.CheckedBalanceOperation(context.Background(), &conflicting, conflicting.Entry(), &model.Checks{Funds: func(*model.Funds) error {
+		return nil
+	}})
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, berrors.IdempotencyConflict, e.Code)
+}
+
+func TestListOperations(t *testing.T) {
+	profileID := uuid.New()
+	for _, amount := range []int64{10, -5, 20, -5, 30} {
+		operation := &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: profileID,
+			Operation: decimal.NewFromInt(amount),
+			Currency:  model.DefaultCurrency,
+		}
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	filter := &model.OperationFilter{ProfileID: profileID, Sign: model.Deposits, Limit: 2}
+	operations, err := pg.ListOperations(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	require.Equal(t, "10", operations[0].Operation.String())
+	require.Equal(t, "20", operations[1].Operation.String())
+	require.False(t, operations[1].OperationTime.Before(operations[0].OperationTime))
+	filter.After = &model.Cursor{OperationTime: operations[1].OperationTime, BalanceID: operations[1].BalanceID}
+	operations, err = pg.ListOperations(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	require.Equal(t, "30", operations[0].Operation.String())
+	operations, err = pg.ListOperations(context.Background(), &model.OperationFilter{ProfileID: profileID, Sign: model.Withdrawals, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	operations, err = pg.ListOperations(context.Background(), &model.OperationFilter{ProfileID: profileID, From: time.Now().Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
+
+func TestListOperationsByType(t *testing.T) {
+	profileID := uuid.New()
+	for _, operation := range []*model.Balance{
+		{Operation: decimal.NewFromInt(100), OperationType: model.Deposit},
+		{Operation: decimal.NewFromInt(5), OperationType: model.Bonus, Description: "welcome bonus"},
+		{Operation: decimal.NewFromInt(-1), OperationType: model.Fee, Metadata: map[string]string{"order": "42"}},
+	} {
+		operation.BalanceID = uuid.New()
+		operation.ProfileID = profileID
+		operation.Currency = model.DefaultCurrency
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	operations, err := pg.ListOperations(context.Background(), &model.OperationFilter{
+		ProfileID: profileID,
+		Types:     []model.OperationType{model.Bonus, model.Fee},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	require.Equal(t, model.Bonus, operations[0].OperationType)
+	require.Equal(t, "welcome bonus", operations[0].Description)
+	require.Equal(t, model.Fee, operations[1].OperationType)
+	require.Equal(t, map[string]string{"order": "42"}, operations[1].Metadata)
+}
+
+func TestReverseOperation(t *testing.T) {
+	profileID := uuid.New()
+	deposit := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     profileID,
+		Operation:     decimal.NewFromInt(100),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Deposit,
+	}
+	err := balanceOperation(context.Background(), deposit, deposit.Entry())
+	require.NoError(t, err)
+	original, err := pg.GetOperation(context.Background(), deposit.BalanceID)
+	require.NoError(t, err)
+	require.Equal(t, model.Deposit, original.OperationType)
+	reversal := original.Reverse(uuid.New(), "mistake")
+	err = pg.ReverseOperation(context.Background(), reversal, reversal.Entry(), &model.Checks{Funds: func(funds *model.Funds) error {
+		require.Equal(t, "100", funds.Available.String())
+		return nil
+	}})
+	require.NoError(t, err)
+	require.False(t, reversal.OperationTime.IsZero())
+	replayed := original.Reverse(reversal.BalanceID, "mistake")
+	err = pg.ReverseOperation(context.Background(), replayed, replayed.Entry(), &model.Checks{})
+	require.NoError(t, err)
+	require.Equal(t, reversal.OperationTime, replayed.OperationTime)
+	again := original.Reverse(uuid.New(), "")
+	err = pg.ReverseOperation(context.Background(), again, again.Entry(), &model.Checks{})
+	require.ErrorIs(t, err, berrors.New(berrors.DuplicateOperation))
+	recorded, err := pg.GetOperation(context.Background(), reversal.BalanceID)
+	require.NoError(t, err)
+	require.Equal(t, deposit.BalanceID, recorded.ReversalOf.UUID)
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+	_, err = pg.GetOperation(context.Background(), uuid.New())
+	require.ErrorIs(t, err, berrors.New(berrors.OperationNotFound))
+}
+
+func TestTransfer(t *testing.T) {
+	fromProfileID, toProfileID := uuid.New(), uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: fromProfileID,
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: fromProfileID,
+		ToProfileID:   toProfileID,
+		Amount:        decimal.NewFromInt(40),
+		Currency:      model.DefaultCurrency,
+	}
+	check := func(operation *model.Balance) *model.Checks {
+		if operation.Operation.IsPositive() {
+			return &model.Checks{}
+		}
+		return &model.Checks{Funds: func(funds *model.Funds) error {
+			if funds.Available.LessThan(transfer.Amount) {
+				return berrors.New(berrors.NotEnoughMoney)
+			}
+			return nil
+		}}
+	}
+	err = pg.Transfer(context.Background(), transfer, transfer.Entry(), check)
+	require.NoError(t, err)
+	err = pg.Transfer(context.Background(), transfer, transfer.Entry(), check)
+	require.NoError(t, err)
+	funds, err := pg.GetBalance(context.Background(), fromProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "60", funds.Total.String())
+	funds, err = pg.GetBalance(context.Background(), toProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "40", funds.Total.String())
+	operations, err := pg.ListOperations(context.Background(), &model.OperationFilter{ProfileID: toProfileID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	require.Equal(t, transfer.TransferID, operations[0].TransferID.UUID)
+	debit, err := pg.GetOperation(context.Background(), transfer.Debit().BalanceID)
+	require.NoError(t, err)
+	require.Equal(t, "-40", debit.Operation.String())
+	sameID := &model.Balance{
+		BalanceID: transfer.TransferID,
+		ProfileID: fromProfileID,
+		Operation: decimal.NewFromInt(5),
+		Currency:  model.DefaultCurrency,
+	}
+	err = balanceOperation(context.Background(), sameID, sameID.Entry())
+	require.NoError(t, err)
+	require.False(t, sameID.Replayed)
+	transfer.TransferID = uuid.New()
+	transfer.Amount = decimal.NewFromInt(70)
+	err = pg.Transfer(context.Background(), transfer, transfer.Entry(), check)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	funds, err = pg.GetBalance(context.Background(), toProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "40", funds.Total.String())
+}
+
+func TestOppositeParallelTransfers(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	for _, profileID := range []uuid.UUID{first, second} {
+		operation := &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: profileID,
+			Operation: decimal.NewFromInt(1000),
+			Currency:  model.DefaultCurrency,
+		}
+		err := balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []error
+	)
+	for i := 0; i < 20; i++ {
+		from, to := first, second
+		if i%2 == 1 {
+			from, to = second, first
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			transfer := &model.Transfer{
+				TransferID:    uuid.New(),
+				FromProfileID: from,
+				ToProfileID:   to,
+				Amount:        decimal.NewFromInt(10),
+				Currency:      model.DefaultCurrency,
+			}
+			errTransfer := pg.Transfer(context.Background(), transfer, transfer.Entry(), noChecks)
+			if errTransfer != nil {
+				mu.Lock()
+				failed = append(failed, errTransfer)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Empty(t, failed)
+	funds, err := pg.GetBalance(context.Background(), first, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "1000", funds.Total.String())
+}
+
+func TestReconcile(t *testing.T) {
+	profileID := uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: profileID,
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	drifts, err := pg.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+	_, err = pg.pool.Exec(context.Background(), "UPDATE ledger_accounts SET money = money + 1 WHERE kind = $1 AND profileid = $2",
+		string(model.WalletAccount), profileID)
+	require.NoError(t, err)
+	drifts, err = pg.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	require.Equal(t, profileID, drifts[0].ProfileID)
+	require.Equal(t, "100", drifts[0].Ledger.String())
+	require.Equal(t, "101", drifts[0].Snapshot.String())
+	_, err = pg.pool.Exec(context.Background(), "UPDATE ledger_accounts SET money = money - 1 WHERE kind = $1 AND profileid = $2",
+		string(model.WalletAccount), profileID)
+	require.NoError(t, err)
+}
+
+func TestListenBalanceChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listening := make(chan struct{})
+	changes := make(chan *model.BalanceChange, 10)
+	done := make(chan error)
+	go func() {
+		done <- pg.ListenBalanceChanges(ctx, func() { close(listening) }, func(change *model.BalanceChange) {
+			changes <- change
+		})
+	}()
+	<-listening
+	profileID := uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: profileID,
+		Operation: decimal.NewFromInt(100),
+		Currency:  "EUR",
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	for change := range changes {
+		if change.ProfileID == profileID {
+			require.Equal(t, "EUR", change.Currency)
+			break
+		}
+	}
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
 }
@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// usageKey identifies limits which apply to operations of profile in currency and direction
+type usageKey struct {
+	profileID uuid.UUID
+	currency  string
+	direction model.LimitDirection
+}
+
+// batchState is the state of profiles of batch which is read once and changed by accepted operations
+type batchState struct {
+	accounts map[uuid.UUID]*model.AccountState
+	funds    map[model.Account]*model.Funds
+	usages   map[usageKey][]*model.LimitUsage
+}
+
+// BatchBalanceOperations locks all profiles of batch, reads state of every profile once and applies checks of operations
+// in order of batch to the state changed by previous operations, then records accepted operations with multi-row inserts
+// in one transaction. Error of failed check is set to its operation, in atomic mode it is returned and nothing is recorded.
+// Replays of already recorded operations do nothing and aren`t checked again.
+func (p *PgRepository) BatchBalanceOperations(ctx context.Context, batch []*model.BatchOperation, atomic bool) error {
+	ctx, span := tracing.Start(ctx, "repository.BatchBalanceOperations", tracing.BatchSizeKey.Int(len(batch)))
+	defer span.End()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		profileIDs := make([]uuid.UUID, 0, len(batch))
+		locked := make(map[uuid.UUID]bool, len(batch))
+		for _, operation := range batch {
+			if !locked[operation.Balance.ProfileID] {
+				locked[operation.Balance.ProfileID] = true
+				profileIDs = append(profileIDs, operation.Balance.ProfileID)
+			}
+		}
+		err := lockProfiles(ctx, tx, profileIDs...)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		pending, err := findReplays(ctx, tx, batch)
+		if err != nil {
+			return fmt.Errorf("findReplays %w", err)
+		}
+		state, err := readBatchState(ctx, tx, pending)
+		if err != nil {
+			return fmt.Errorf("readBatchState %w", err)
+		}
+		accepted := make([]*model.BatchOperation, 0, len(pending))
+		for _, operation := range batch {
+			if operation.Err == nil && pending[operation] {
+				operation.Err = state.apply(operation)
+			}
+			if operation.Err != nil {
+				if atomic {
+					return operation.Err
+				}
+				continue
+			}
+			if pending[operation] {
+				accepted = append(accepted, operation)
+			}
+		}
+		return insertOperations(ctx, tx, accepted)
+	})
+}
+
+// findReplays looks for already recorded operations of batch with one query, it returns operations which aren`t
+// recorded yet and sets the business error to operations whose id was used for another operation
+func findReplays(ctx context.Context, tx pgx.Tx, batch []*model.BatchOperation) (map[*model.BatchOperation]bool, error) {
+	balanceIDs := make([]uuid.UUID, 0, len(batch))
+	for _, operation := range batch {
+		balanceIDs = append(balanceIDs, operation.Balance.BalanceID)
+	}
+	rows, err := tx.Query(ctx, "SELECT "+operationColumns+" FROM balance WHERE balanceid = ANY($1)", balanceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	recorded := make(map[uuid.UUID]*model.Balance)
+
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanOperation %w", err)
+		}
+		recorded[operation.BalanceID] = operation
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+	pending := make(map[*model.BatchOperation]bool, len(batch))
+	for _, operation := range batch {
+		replayed, ok := recorded[operation.Balance.BalanceID]
+		if !ok {
+			pending[operation] = true
+			continue
+		}
+		_, operation.Err = replayOf(ctx, replayed, operation.Balance)
+	}
+	return pending, nil
+}
+
+// readBatchState reads only the state which is needed by checks of pending operations
+func readBatchState(ctx context.Context, tx pgx.Tx, pending map[*model.BatchOperation]bool) (*batchState, error) {
+	ctx, span := tracing.Start(ctx, "repository.readBatchState")
+	defer span.End()
+	state := &batchState{
+		accounts: make(map[uuid.UUID]*model.AccountState),
+		funds:    make(map[model.Account]*model.Funds),
+		usages:   make(map[usageKey][]*model.LimitUsage),
+	}
+	for operation := range pending {
+		balance, checks := operation.Balance, operation.Checks
+		if _, ok := state.accounts[balance.ProfileID]; checks.Account != nil && !ok {
+			account, err := readAccount(ctx, tx, balance.ProfileID)
+			if err != nil {
+				return nil, fmt.Errorf("readAccount %w", err)
+			}
+			state.accounts[balance.ProfileID] = account
+		}
+		wallet := model.Wallet(balance.ProfileID, balance.Currency)
+		if _, ok := state.funds[wallet]; checks.Funds != nil && !ok {
+			funds, err := readFunds(ctx, tx, balance.ProfileID, balance.Currency)
+			if err != nil {
+				return nil, fmt.Errorf("readFunds %w", err)
+			}
+			state.funds[wallet] = funds
+		}
+		key := usageKey{profileID: balance.ProfileID, currency: balance.Currency, direction: balance.Direction()}
+		if _, ok := state.usages[key]; checks.Limits != nil && !ok {
+			usages, err := readLimitUsages(ctx, tx, balance)
+			if err != nil {
+				return nil, fmt.Errorf("readLimitUsages %w", err)
+			}
+			state.usages[key] = usages
+		}
+	}
+	return state, nil
+}
+
+// apply checks the operation against the state and changes the state if the operation is accepted
+func (s *batchState) apply(operation *model.BatchOperation) error {
+	balance, checks := operation.Balance, operation.Checks
+	wallet := model.Wallet(balance.ProfileID, balance.Currency)
+	key := usageKey{profileID: balance.ProfileID, currency: balance.Currency, direction: balance.Direction()}
+	if checks.Account != nil {
+		err := checks.Account(s.accounts[balance.ProfileID])
+		if err != nil {
+			return err
+		}
+	}
+	if checks.Funds != nil {
+		err := checks.Funds(s.funds[wallet])
+		if err != nil {
+			return err
+		}
+	}
+	if checks.Limits != nil {
+		err := checks.Limits(s.usages[key])
+		if err != nil {
+			return err
+		}
+	}
+	if funds, ok := s.funds[wallet]; ok {
+		funds.Total = funds.Total.Add(balance.Operation)
+		funds.Available = funds.Available.Add(balance.Operation)
+	}
+	for _, usage := range s.usages[key] {
+		if usage.Limit.Period.Window() > 0 {
+			usage.Used = usage.Used.Add(balance.Operation.Abs())
+		}
+	}
+	return nil
+}
+
+// insertOperations records operations with their journal entries and events by multi-row inserts and notifies
+// watchers of every changed balance once
+func insertOperations(ctx context.Context, tx pgx.Tx, batch []*model.BatchOperation) error {
+	ctx, span := tracing.Start(ctx, "repository.insertOperations", tracing.BatchSizeKey.Int(len(batch)))
+	defer span.End()
+	if len(batch) == 0 {
+		return nil
+	}
+	const columns = 9
+	args := make([]any, 0, len(batch)*columns)
+	operations := make(map[uuid.UUID]*model.Balance, len(batch))
+	entries := make([]*model.JournalEntry, 0, len(batch))
+	for _, operation := range batch {
+		balance := operation.Balance
+		metadata := balance.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		args = append(args, balance.BalanceID, balance.ProfileID, balance.Operation, balance.Currency, balance.TransferID,
+			string(balance.OperationType), balance.Description, metadata, balance.ReversalOf)
+		operations[balance.BalanceID] = balance
+		entries = append(entries, operation.Entry)
+	}
+	rows, err := tx.Query(ctx, `INSERT INTO balance (balanceid, profileid, operation, currency, transferid, operationtype, description,
+		metadata, reversalof) VALUES `+valuesList(len(batch), columns)+` ON CONFLICT (balanceid) DO NOTHING RETURNING balanceid, operationtime`,
+		args...)
+	if err != nil {
+		return fmt.Errorf("query %w", err)
+	}
+	inserted := 0
+	for rows.Next() {
+		var (
+			balanceID     uuid.UUID
+			operationTime time.Time
+		)
+		err = rows.Scan(&balanceID, &operationTime)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("scan %w", err)
+		}
+		operations[balanceID].OperationTime = operationTime
+		inserted++
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows %w", err)
+	}
+	if inserted != len(batch) {
+		return berrors.New(berrors.IdempotencyConflict)
+	}
+	err = insertEntries(ctx, tx, entries)
+	if err != nil {
+		return fmt.Errorf("insertEntries %w", err)
+	}
+	return insertBatchEvents(ctx, tx, batch)
+}
+
+// insertBatchEvents adds events of operations to outbox with one insert and notifies watchers of changed balances
+func insertBatchEvents(ctx context.Context, tx pgx.Tx, batch []*model.BatchOperation) error {
+	const columns = 4
+	args := make([]any, 0, len(batch)*columns)
+	changed := make(map[model.Account]bool, len(batch))
+	for _, operation := range batch {
+		payload, err := json.Marshal(operation.Balance)
+		if err != nil {
+			return fmt.Errorf("marshal %w", err)
+		}
+		args = append(args, uuid.New(), model.OperationTopic, payload, string(model.EventPending))
+		changed[model.Wallet(operation.Balance.ProfileID, operation.Balance.Currency)] = true
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO outbox (eventid, topic, payload, status) VALUES "+valuesList(len(batch), columns), args...)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	for wallet := range changed {
+		err = notifyChange(ctx, tx, wallet.ProfileID, wallet.Currency)
+		if err != nil {
+			return fmt.Errorf("notifyChange %w", err)
+		}
+	}
+	return nil
+}
+
+// insertEntries records journal entries like insertEntry, but with multi-row inserts, and adds the sum of postings
+// to maintained balance of every wallet once
+func insertEntries(ctx context.Context, tx pgx.Tx, entries []*model.JournalEntry) error {
+	entryIDs := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		entryIDs = append(entryIDs, entry.EntryID)
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO journal_entries (entryid) VALUES "+valuesList(len(entries), 1), entryIDs...)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	const columns = 3
+	var (
+		args       []any
+		accountIDs = make(map[model.Account]uuid.UUID)
+		wallets    = make(map[uuid.UUID]decimal.Decimal)
+	)
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			accountID, ok := accountIDs[posting.Account]
+			if !ok {
+				accountID, err = ensureAccount(ctx, tx, posting.Account)
+				if err != nil {
+					return fmt.Errorf("ensureAccount %w", err)
+				}
+				accountIDs[posting.Account] = accountID
+			}
+			args = append(args, entry.EntryID, accountID, posting.Amount)
+			if posting.Account.Kind == model.WalletAccount {
+				wallets[accountID] = wallets[accountID].Add(posting.Amount)
+			}
+		}
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO postings (entryid, accountid, amount) VALUES "+valuesList(len(args)/columns, columns), args...)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	for accountID, amount := range wallets {
+		_, err = tx.Exec(ctx, "UPDATE ledger_accounts SET money = money + $1, updatedtime = NOW() WHERE accountid = $2", amount, accountID)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+	}
+	return nil
+}
+
+// valuesList returns placeholders of multi-row insert of rows with the number of columns
+func valuesList(rows, columns int) string {
+	var values strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			values.WriteString(", ")
+		}
+		values.WriteString("(")
+		for column := 1; column <= columns; column++ {
+			if column > 1 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "$%d", row*columns+column)
+		}
+		values.WriteString(")")
+	}
+	return values.String()
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func batchOperation(profileID uuid.UUID, amount int64) *model.BatchOperation {
+	balance := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     profileID,
+		Operation:     decimal.NewFromInt(amount),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.DefaultOperationType(decimal.NewFromInt(amount)),
+	}
+	checks := &model.Checks{}
+	if balance.Operation.IsNegative() {
+		checks.Funds = holdCheck(balance.Operation.Abs())
+	}
+	return &model.BatchOperation{Balance: balance, Entry: balance.Entry(), Checks: checks}
+}
+
+func TestBatchBalanceOperations(t *testing.T) {
+	profileID := uuid.New()
+	batch := []*model.BatchOperation{
+		batchOperation(profileID, 100),
+		batchOperation(profileID, -70),
+		batchOperation(profileID, -50),
+		batchOperation(uuid.New(), 10),
+	}
+	err := pg.BatchBalanceOperations(context.Background(), batch, false)
+	require.NoError(t, err)
+	require.NoError(t, batch[0].Err)
+	require.NoError(t, batch[1].Err)
+	require.ErrorIs(t, batch[2].Err, berrors.New(berrors.NotEnoughMoney))
+	require.NoError(t, batch[3].Err)
+	require.False(t, batch[0].Balance.OperationTime.IsZero())
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "30", funds.Total.String())
+
+	batch[2].Err = nil
+	err = pg.BatchBalanceOperations(context.Background(), batch[:2], false)
+	require.NoError(t, err)
+	require.NoError(t, batch[1].Err)
+	funds, err = pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "30", funds.Total.String())
+}
+
+func TestAtomicBatchBalanceOperations(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	batch := []*model.BatchOperation{
+		batchOperation(profileID, -60),
+		batchOperation(profileID, 20),
+		batchOperation(profileID, -70),
+	}
+	err := pg.BatchBalanceOperations(context.Background(), batch, true)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "100", funds.Total.String())
+	operations, err := pg.ListOperations(context.Background(), &model.OperationFilter{ProfileID: profileID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+)
+
+// SetCreditLimit creates or replaces the credit limit of profile in currency
+func (p *PgRepository) SetCreditLimit(ctx context.Context, line *model.CreditLine) error {
+	err := p.pool.QueryRow(ctx, `INSERT INTO credit_line (profileid, currency, creditlimit) VALUES ($1, $2, $3)
+		ON CONFLICT (profileid, currency) DO UPDATE SET creditlimit = EXCLUDED.creditlimit, updatedtime = NOW()
+		RETURNING updatedtime`,
+		line.ProfileID, line.Currency, line.CreditLimit).Scan(&line.UpdatedTime)
+	if err != nil {
+		return fmt.Errorf("queryRow %w", err)
+	}
+	return nil
+}
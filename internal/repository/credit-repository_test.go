@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreditLimit(t *testing.T) {
+	profileID := uuid.New()
+	line := &model.CreditLine{ProfileID: profileID, Currency: model.DefaultCurrency, CreditLimit: decimal.NewFromInt(50)}
+	err := pg.SetCreditLimit(context.Background(), line)
+	require.NoError(t, err)
+	require.False(t, line.UpdatedTime.IsZero())
+	withdraw := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     profileID,
+		Operation:     decimal.NewFromInt(-40),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Withdrawal,
+	}
+	err = pg.CheckedBalanceOperation(context.Background(), withdraw, withdraw.Entry(), &model.Checks{Funds: func(funds *model.Funds) error {
+		require.True(t, funds.Available.IsZero())
+		require.Equal(t, "50", funds.CreditLimit.String())
+		return nil
+	}})
+	require.NoError(t, err)
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "-40", funds.Total.String())
+	require.Equal(t, "10", funds.Spendable().String())
+}
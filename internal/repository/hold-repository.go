@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Hold locks the profile and reserves the amount of hold until ttl passes if checks accept state of profile
+// for the reservation of hold. Replay of already recorded hold with the same id returns it without reserving money again.
+func (p *PgRepository) Hold(ctx context.Context, hold *model.Hold, ttl time.Duration, checks *model.Checks) error {
+	ctx, span := tracing.Start(ctx, "repository.Hold", tracing.ProfileIDKey.String(hold.ProfileID.String()))
+	defer span.End()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := lockProfiles(ctx, tx, hold.ProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		recorded, err := readHold(ctx, tx, hold.HoldID, false)
+		if err != nil && !errors.Is(err, berrors.New(berrors.HoldNotFound)) {
+			return fmt.Errorf("readHold %w", err)
+		}
+		if recorded != nil {
+			if recorded.ProfileID != hold.ProfileID || !recorded.Amount.Equal(hold.Amount) || recorded.Currency != hold.Currency {
+				return berrors.New(berrors.IdempotencyConflict)
+			}
+			*hold = *recorded
+			return nil
+		}
+		err = applyChecks(ctx, tx, hold.Reservation(), checks)
+		if err != nil {
+			return err
+		}
+		hold.Status = model.HoldActive
+		err = tx.QueryRow(ctx, `INSERT INTO hold (holdid, profileid, currency, amount, status, expirestime)
+			VALUES ($1, $2, $3, $4, $5, NOW() + $6::interval) RETURNING expirestime`,
+			hold.HoldID, hold.ProfileID, hold.Currency, hold.Amount, string(hold.Status), ttl).Scan(&hold.ExpiresTime)
+		if err != nil {
+			return fmt.Errorf("queryRow %w", err)
+		}
+		return notifyChange(ctx, tx, hold.ProfileID, hold.Currency)
+	})
+}
+
+// GetHold returns the hold by its id or the business error if it doesn`t exist
+func (p *PgRepository) GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error) {
+	return readHold(ctx, p.pool, holdID, false)
+}
+
+// CaptureHold locks the profile of hold, withdraws the amount from active hold and returns the rest of hold
+// to available balance if checks accept state of profile for the capture, zero amount captures the whole hold.
+// Replay of capture with the same amount returns the recorded operation.
+func (p *PgRepository) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal,
+	checks func(capture *model.Balance) *model.Checks) (*model.Balance, error) {
+	ctx, span := tracing.Start(ctx, "repository.CaptureHold", tracing.HoldIDKey.String(holdID.String()))
+	defer span.End()
+	var operation *model.Balance
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		hold, err := readHold(ctx, tx, holdID, false)
+		if err != nil {
+			return err
+		}
+		err = lockProfiles(ctx, tx, hold.ProfileID)
+		if err != nil {
+			return fmt.Errorf("lockProfiles %w", err)
+		}
+		hold, err = readHold(ctx, tx, holdID, true)
+		if err != nil {
+			return err
+		}
+		if amount.IsZero() {
+			amount = hold.Amount
+		}
+		if hold.Status == model.HoldCaptured {
+			if !hold.Captured.Equal(amount) {
+				return berrors.New(berrors.DuplicateOperation)
+			}
+			operation = hold.Capture()
+			_, err = findReplay(ctx, tx, operation)
+			return err
+		}
+		if hold.Status != model.HoldActive {
+			return berrors.New(berrors.HoldNotActive)
+		}
+		if amount.GreaterThan(hold.Amount) {
+			return berrors.New(berrors.InvalidAmount).
+				WithMetadata(berrors.RequiredKey, amount.String()).
+				WithMetadata(berrors.AvailableKey, hold.Amount.String()).
+				WithMetadata(berrors.CurrencyKey, hold.Currency)
+		}
+		hold.Captured = amount
+		operation = hold.Capture()
+		err = applyChecks(ctx, tx, operation, checks(operation))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE hold SET status = $1, captured = $2 WHERE holdid = $3",
+			string(model.HoldCaptured), hold.Captured, hold.HoldID)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+		return insertOperation(ctx, tx, operation, operation.Entry())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// ReleaseHold returns the whole amount of active hold to available balance, release of released hold does nothing
+func (p *PgRepository) ReleaseHold(ctx context.Context, holdID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "repository.ReleaseHold", tracing.HoldIDKey.String(holdID.String()))
+	defer span.End()
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		hold, err := readHold(ctx, tx, holdID, true)
+		if err != nil {
+			return err
+		}
+		switch hold.Status {
+		case model.HoldReleased:
+			return nil
+		case model.HoldActive:
+		case model.HoldCaptured, model.HoldExpired:
+			return berrors.New(berrors.HoldNotActive)
+		}
+		_, err = tx.Exec(ctx, "UPDATE hold SET status = $1 WHERE holdid = $2", string(model.HoldReleased), hold.HoldID)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+		return notifyChange(ctx, tx, hold.ProfileID, hold.Currency)
+	})
+}
+
+// ExpireHolds marks active holds which weren`t captured before their expiration time as expired
+// and returns their number, listeners of every wallet with expired holds are notified once
+func (p *PgRepository) ExpireHolds(ctx context.Context) (int64, error) {
+	var expired int64
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `UPDATE hold SET status = $1 WHERE status = $2 AND expirestime <= NOW()
+			RETURNING profileid, currency`, string(model.HoldExpired), string(model.HoldActive))
+		if err != nil {
+			return fmt.Errorf("query %w", err)
+		}
+		changed := make(map[model.Account]bool)
+		for rows.Next() {
+			var (
+				profileID uuid.UUID
+				currency  string
+			)
+			err = rows.Scan(&profileID, &currency)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("scan %w", err)
+			}
+			changed[model.Wallet(profileID, currency)] = true
+			expired++
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("rows %w", err)
+		}
+		for wallet := range changed {
+			err = notifyChange(ctx, tx, wallet.ProfileID, wallet.Currency)
+			if err != nil {
+				return fmt.Errorf("notifyChange %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// readHold returns the hold by its id or the business error if it doesn`t exist, the hold is locked
+// until the end of transaction if forUpdate is set. Active hold which is already expired is returned as expired.
+func readHold(ctx context.Context, q querier, holdID uuid.UUID, forUpdate bool) (*model.Hold, error) {
+	var (
+		hold     model.Hold
+		status   string
+		captured decimal.NullDecimal
+	)
+	query := `SELECT holdid, profileid, currency, amount, captured,
+		CASE WHEN status = $2 AND expirestime <= NOW() THEN $3 ELSE status END, expirestime
+		FROM hold WHERE holdid = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	err := q.QueryRow(ctx, query, holdID, string(model.HoldActive), string(model.HoldExpired)).
+		Scan(&hold.HoldID, &hold.ProfileID, &hold.Currency, &hold.Amount, &captured, &status, &hold.ExpiresTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, berrors.New(berrors.HoldNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("queryRow %w", err)
+	}
+	hold.Status = model.HoldStatus(status)
+	hold.Captured = captured.Decimal
+	return &hold, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func depositForHold(t *testing.T, amount int64) uuid.UUID {
+	profileID := uuid.New()
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: profileID,
+		Operation: decimal.NewFromInt(amount),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	return profileID
+}
+
+func holdCheck(amount decimal.Decimal) func(funds *model.Funds) error {
+	return func(funds *model.Funds) error {
+		if funds.Available.LessThan(amount) {
+			return berrors.New(berrors.NotEnoughMoney)
+		}
+		return nil
+	}
+}
+
+// noChecks returns empty checks of operation
+func noChecks(*model.Balance) *model.Checks {
+	return &model.Checks{}
+}
+
+func TestHoldWithCapture(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(70),
+		Currency:  model.DefaultCurrency,
+	}
+	err := pg.Hold(context.Background(), hold, time.Hour, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	require.Equal(t, model.HoldActive, hold.Status)
+	err = pg.Hold(context.Background(), hold, time.Hour, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "100", funds.Total.String())
+	require.Equal(t, "30", funds.Available.String())
+	err = pg.Hold(context.Background(), &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(40),
+		Currency:  model.DefaultCurrency,
+	}, time.Hour, &model.Checks{Funds: holdCheck(decimal.NewFromInt(40))})
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+
+	operation, err := pg.CaptureHold(context.Background(), hold.HoldID, decimal.NewFromInt(50), noChecks)
+	require.NoError(t, err)
+	require.Equal(t, "-50", operation.Operation.String())
+	replayed, err := pg.CaptureHold(context.Background(), hold.HoldID, decimal.NewFromInt(50), noChecks)
+	require.NoError(t, err)
+	require.Equal(t, operation.BalanceID, replayed.BalanceID)
+	require.True(t, replayed.Replayed)
+	require.Equal(t, operation.OperationTime, replayed.OperationTime)
+	_, err = pg.CaptureHold(context.Background(), hold.HoldID, decimal.NewFromInt(60), noChecks)
+	require.ErrorIs(t, err, berrors.New(berrors.DuplicateOperation))
+	funds, err = pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "50", funds.Total.String())
+	require.Equal(t, "50", funds.Available.String())
+	err = pg.ReleaseHold(context.Background(), hold.HoldID)
+	require.ErrorIs(t, err, berrors.New(berrors.HoldNotActive))
+}
+
+func TestHoldCaptureMoreThanHeld(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	}
+	err := pg.Hold(context.Background(), hold, time.Hour, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	_, err = pg.CaptureHold(context.Background(), hold.HoldID, decimal.NewFromInt(11), noChecks)
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidAmount))
+	operation, err := pg.CaptureHold(context.Background(), hold.HoldID, decimal.Zero, noChecks)
+	require.NoError(t, err)
+	require.Equal(t, "-10", operation.Operation.String())
+	_, err = pg.CaptureHold(context.Background(), uuid.New(), decimal.Zero, noChecks)
+	require.ErrorIs(t, err, berrors.New(berrors.HoldNotFound))
+}
+
+func TestReleaseHold(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := pg.Hold(context.Background(), hold, time.Hour, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	err = pg.ReleaseHold(context.Background(), hold.HoldID)
+	require.NoError(t, err)
+	err = pg.ReleaseHold(context.Background(), hold.HoldID)
+	require.NoError(t, err)
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "100", funds.Available.String())
+	_, err = pg.CaptureHold(context.Background(), hold.HoldID, decimal.Zero, noChecks)
+	require.ErrorIs(t, err, berrors.New(berrors.HoldNotActive))
+}
+
+func TestExpireHolds(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := pg.Hold(context.Background(), hold, time.Millisecond, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	funds, err := pg.GetBalance(context.Background(), profileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "100", funds.Available.String())
+	_, err = pg.CaptureHold(context.Background(), hold.HoldID, decimal.Zero, noChecks)
+	require.ErrorIs(t, err, berrors.New(berrors.HoldNotActive))
+	expired, err := pg.ExpireHolds(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+}
+
+func TestCaptureHoldOfFrozenProfile(t *testing.T) {
+	profileID := depositForHold(t, 100)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: profileID,
+		Amount:    decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	}
+	err := pg.Hold(context.Background(), hold, time.Hour, &model.Checks{Funds: holdCheck(hold.Amount)})
+	require.NoError(t, err)
+	_, err = pg.ChangeAccount(context.Background(), &model.AccountChange{ProfileID: profileID, Actor: "compliance", Reason: "fraud"},
+		func(state *model.AccountState) error {
+			state.Status = model.AccountFrozen
+			return nil
+		})
+	require.NoError(t, err)
+	_, err = pg.CaptureHold(context.Background(), hold.HoldID, decimal.Zero, func(capture *model.Balance) *model.Checks {
+		return &model.Checks{Account: func(state *model.AccountState) error {
+			if !state.Allows(capture) {
+				return berrors.New(berrors.AccountFrozen)
+			}
+			return nil
+		}}
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+	recorded, err := readHold(context.Background(), pg.pool, hold.HoldID, false)
+	require.NoError(t, err)
+	require.Equal(t, model.HoldActive, recorded.Status)
+}
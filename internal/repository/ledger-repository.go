@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// insertEntry records the journal entry with its postings and adds postings to maintained balances of wallets.
+// Balances of accounts of the service aren`t maintained, because every operation would wait for the same row,
+// they are counted from postings. Database rejects the transaction at commit if postings don`t sum to zero.
+func insertEntry(ctx context.Context, tx pgx.Tx, entry *model.JournalEntry) error {
+	_, err := tx.Exec(ctx, "INSERT INTO journal_entries (entryid) VALUES ($1)", entry.EntryID)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	for _, posting := range entry.Postings {
+		accountID, err := ensureAccount(ctx, tx, posting.Account)
+		if err != nil {
+			return fmt.Errorf("ensureAccount %w", err)
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO postings (entryid, accountid, amount) VALUES ($1, $2, $3)",
+			entry.EntryID, accountID, posting.Amount)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+		if posting.Account.Kind != model.WalletAccount {
+			continue
+		}
+		_, err = tx.Exec(ctx, "UPDATE ledger_accounts SET money = money + $1, updatedtime = NOW() WHERE accountid = $2",
+			posting.Amount, accountID)
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureAccount returns the id of ledger account and opens the account if it doesn`t exist yet
+func ensureAccount(ctx context.Context, tx pgx.Tx, account model.Account) (uuid.UUID, error) {
+	var accountID uuid.UUID
+	err := tx.QueryRow(ctx, "SELECT accountid FROM ledger_accounts WHERE kind = $1 AND profileid = $2 AND currency = $3",
+		string(account.Kind), account.ProfileID, account.Currency).Scan(&accountID)
+	if err == nil {
+		return accountID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("queryRow %w", err)
+	}
+	err = tx.QueryRow(ctx, `INSERT INTO ledger_accounts (kind, profileid, currency) VALUES ($1, $2, $3)
+		ON CONFLICT (kind, profileid, currency) DO UPDATE SET kind = EXCLUDED.kind RETURNING accountid`,
+		string(account.Kind), account.ProfileID, account.Currency).Scan(&accountID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("queryRow %w", err)
+	}
+	return accountID, nil
+}
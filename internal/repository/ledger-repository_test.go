@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// postingsOf returns amounts of postings of entry by kinds of their accounts
+func postingsOf(t *testing.T, entryID uuid.UUID) map[model.AccountKind][]string {
+	rows, err := pg.pool.Query(context.Background(), `SELECT a.kind, p.amount FROM postings p
+		JOIN ledger_accounts a ON a.accountid = p.accountid WHERE p.entryid = $1 ORDER BY p.amount`, entryID)
+	require.NoError(t, err)
+	defer rows.Close()
+	postings := make(map[model.AccountKind][]string)
+	for rows.Next() {
+		var (
+			kind   string
+			amount decimal.Decimal
+		)
+		require.NoError(t, rows.Scan(&kind, &amount))
+		postings[model.AccountKind(kind)] = append(postings[model.AccountKind(kind)], amount.String())
+	}
+	require.NoError(t, rows.Err())
+	return postings
+}
+
+func TestOperationPostings(t *testing.T) {
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	require.Equal(t, map[model.AccountKind][]string{
+		model.WalletAccount:     {"100"},
+		model.SettlementAccount: {"-100"},
+	}, postingsOf(t, operation.BalanceID))
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: operation.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(40),
+		Currency:      model.DefaultCurrency,
+	}
+	err = pg.Transfer(context.Background(), transfer, transfer.Entry(), noChecks)
+	require.NoError(t, err)
+	require.Equal(t, map[model.AccountKind][]string{
+		model.WalletAccount: {"-40", "40"},
+	}, postingsOf(t, transfer.Debit().BalanceID))
+	funds, err := pg.GetBalance(context.Background(), transfer.ToProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.Equal(t, "40", funds.Total.String())
+}
+
+func TestFeePostings(t *testing.T) {
+	fee := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     uuid.New(),
+		Operation:     decimal.NewFromInt(-5),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Fee,
+	}
+	err := balanceOperation(context.Background(), fee, fee.Entry())
+	require.NoError(t, err)
+	require.Equal(t, map[model.AccountKind][]string{
+		model.WalletAccount: {"-5"},
+		model.FeeAccount:    {"5"},
+	}, postingsOf(t, fee.BalanceID))
+}
+
+func TestUnbalancedEntry(t *testing.T) {
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	entry := operation.Entry()
+	entry.Postings[1].Amount = decimal.NewFromInt(-99)
+	err := balanceOperation(context.Background(), operation, entry)
+	require.Error(t, err)
+	funds, err := pg.GetBalance(context.Background(), operation.ProfileID, model.DefaultCurrency)
+	require.NoError(t, err)
+	require.True(t, funds.Total.IsZero())
+	operations, err := pg.ListOperations(context.Background(), &model.OperationFilter{ProfileID: operation.ProfileID, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// GetLimits returns limits of profile in currency, own limits of profile replace default limits
+// with the same direction and period
+func (p *PgRepository) GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error) {
+	return readLimits(ctx, p.pool, profileID, currency, "")
+}
+
+// SetLimit creates or replaces the limit, the limit with default profile id applies to all profiles without own limit
+func (p *PgRepository) SetLimit(ctx context.Context, limit *model.Limit) error {
+	err := p.pool.QueryRow(ctx, `INSERT INTO operation_limit (profileid, currency, direction, period, amount) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (profileid, currency, direction, period) DO UPDATE SET amount = EXCLUDED.amount, updatedtime = NOW()
+		RETURNING updatedtime`,
+		limit.ProfileID, limit.Currency, string(limit.Direction), string(limit.Period), limit.Amount).Scan(&limit.UpdatedTime)
+	if err != nil {
+		return fmt.Errorf("queryRow %w", err)
+	}
+	return nil
+}
+
+// readLimits returns limits of profile in currency with the direction, empty direction returns limits of both directions
+func readLimits(ctx context.Context, q querier, profileID uuid.UUID, currency string, direction model.LimitDirection) ([]*model.Limit, error) {
+	rows, err := q.Query(ctx, `SELECT DISTINCT ON (direction, period) profileid, currency, direction, period, amount, updatedtime
+		FROM operation_limit WHERE profileid IN ($1, $2) AND currency = $3 AND ($4::varchar = '' OR direction = $4)
+		ORDER BY direction, period, profileid = $1 DESC`,
+		profileID, model.DefaultLimitProfileID, currency, string(direction))
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	var limits []*model.Limit
+
+	for rows.Next() {
+		var (
+			limit             = &model.Limit{}
+			direction, period string
+		)
+		err := rows.Scan(&limit.ProfileID, &limit.Currency, &direction, &period, &limit.Amount, &limit.UpdatedTime)
+		if err != nil {
+			return nil, fmt.Errorf("scan %w", err)
+		}
+		limit.Direction, limit.Period = model.LimitDirection(direction), model.LimitPeriod(period)
+		limits = append(limits, limit)
+	}
+	return limits, rows.Err()
+}
+
+// readLimitUsages returns limits which apply to the operation with sums of operations in their rolling windows.
+// Reversals aren`t limited, so they aren`t counted.
+func readLimitUsages(ctx context.Context, q querier, balance *model.Balance) ([]*model.LimitUsage, error) {
+	direction := balance.Direction()
+	limits, err := readLimits(ctx, q, balance.ProfileID, balance.Currency, direction)
+	if err != nil {
+		return nil, fmt.Errorf("readLimits %w", err)
+	}
+	sign := "operation > 0"
+	if direction == model.WithdrawalLimit {
+		sign = "operation < 0"
+	}
+	usages := make([]*model.LimitUsage, 0, len(limits))
+	for _, limit := range limits {
+		usage := &model.LimitUsage{Limit: limit, Used: decimal.Zero}
+		if window := limit.Period.Window(); window > 0 {
+			err = q.QueryRow(ctx, `SELECT COALESCE(SUM(ABS(operation)), 0) FROM balance
+				WHERE profileid = $1 AND currency = $2 AND operationtime > NOW() - make_interval(secs => $3) AND `+sign+`
+				AND operationtype <> $4`,
+				balance.ProfileID, balance.Currency, window.Seconds(), string(model.Reversal)).Scan(&usage.Used)
+			if err != nil {
+				return nil, fmt.Errorf("queryRow %w", err)
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	profileID := uuid.New()
+	for _, limit := range []*model.Limit{
+		{ProfileID: model.DefaultLimitProfileID, Direction: model.DepositLimit, Period: model.Daily, Amount: decimal.NewFromInt(1000)},
+		{ProfileID: model.DefaultLimitProfileID, Direction: model.WithdrawalLimit, Period: model.Daily, Amount: decimal.NewFromInt(100)},
+		{ProfileID: profileID, Direction: model.WithdrawalLimit, Period: model.Daily, Amount: decimal.NewFromInt(50)},
+	} {
+		limit.Currency = "CHF"
+		err := pg.SetLimit(context.Background(), limit)
+		require.NoError(t, err)
+		require.False(t, limit.UpdatedTime.IsZero())
+	}
+	limits, err := pg.GetLimits(context.Background(), profileID, "CHF")
+	require.NoError(t, err)
+	require.Len(t, limits, 2)
+	require.Equal(t, model.DepositLimit, limits[0].Direction)
+	require.True(t, limits[0].IsDefault())
+	require.Equal(t, model.WithdrawalLimit, limits[1].Direction)
+	require.Equal(t, profileID, limits[1].ProfileID)
+	require.Equal(t, "50", limits[1].Amount.String())
+	limits, err = pg.GetLimits(context.Background(), uuid.New(), "CHF")
+	require.NoError(t, err)
+	require.Len(t, limits, 2)
+	require.Equal(t, "100", limits[1].Amount.String())
+}
+
+func TestLimitUsages(t *testing.T) {
+	profileID := uuid.New()
+	err := pg.SetLimit(context.Background(), &model.Limit{
+		ProfileID: profileID,
+		Currency:  "SEK",
+		Direction: model.WithdrawalLimit,
+		Period:    model.Daily,
+		Amount:    decimal.NewFromInt(50),
+	})
+	require.NoError(t, err)
+	for _, amount := range []int64{100, -20, -10} {
+		operation := &model.Balance{
+			BalanceID:     uuid.New(),
+			ProfileID:     profileID,
+			Operation:     decimal.NewFromInt(amount),
+			Currency:      "SEK",
+			OperationType: model.DefaultOperationType(decimal.NewFromInt(amount)),
+		}
+		err = balanceOperation(context.Background(), operation, operation.Entry())
+		require.NoError(t, err)
+	}
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: profileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(8),
+		Currency:      "SEK",
+	}
+	err = pg.Transfer(context.Background(), transfer, transfer.Entry(), noChecks)
+	require.NoError(t, err)
+	withdraw := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     profileID,
+		Operation:     decimal.NewFromInt(-5),
+		Currency:      "SEK",
+		OperationType: model.Withdrawal,
+	}
+	err = pg.CheckedBalanceOperation(context.Background(), withdraw, withdraw.Entry(), &model.Checks{Limits: func(usages []*model.LimitUsage) error {
+		require.Len(t, usages, 1)
+		require.Equal(t, "38", usages[0].Used.String())
+		require.Equal(t, "12", usages[0].Remaining().String())
+		return nil
+	}})
+	require.NoError(t, err)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// balanceChannel is the channel of PostgreSQL notifications about changes of balances
+const balanceChannel = "balance_changes"
+
+// ListenBalanceChanges listens for changes of balances committed by any replica of service and passes them to handle
+// until the context is done or the connection fails. Listening is called once the connection listens to the channel.
+func (p *PgRepository) ListenBalanceChanges(ctx context.Context, listening func(), handle func(change *model.BalanceChange)) error {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire %w", err)
+	}
+	// the connection is taken from pool, so it doesn`t return to pool still listening the channel
+	pgConn := conn.Hijack()
+	defer func() {
+		_ = pgConn.Close(context.Background())
+	}()
+	_, err = pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{balanceChannel}.Sanitize())
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	listening()
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waitForNotification %w", err)
+		}
+		var change model.BalanceChange
+		err = json.Unmarshal([]byte(notification.Payload), &change)
+		if err != nil {
+			return fmt.Errorf("unmarshal %w", err)
+		}
+		handle(&change)
+	}
+}
+
+// notifyChange notifies listeners about change of balance, the notification is delivered only when transaction commits
+func notifyChange(ctx context.Context, tx pgx.Tx, profileID uuid.UUID, currency string) error {
+	payload, err := json.Marshal(&model.BalanceChange{ProfileID: profileID, Currency: currency})
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	_, err = tx.Exec(ctx, "SELECT pg_notify($1, $2)", balanceChannel, string(payload))
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimEvents returns pending events which are due for publishing, oldest first, and postpones them for lease,
+// so other relays skip them while they are published. Attempts of returned events are already counted.
+func (p *PgRepository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]*model.Event, error) {
+	rows, err := p.pool.Query(ctx, `UPDATE outbox SET attempts = attempts + 1, nextattempttime = NOW() + $3::interval
+		WHERE eventid IN (SELECT eventid FROM outbox WHERE status = $1 AND nextattempttime <= NOW()
+			ORDER BY createdtime LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING eventid, topic, payload, attempts, createdtime`,
+		string(model.EventPending), limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.Event
+
+	for rows.Next() {
+		event := &model.Event{}
+		err := rows.Scan(&event.EventID, &event.Topic, &event.Payload, &event.Attempts, &event.CreatedTime)
+		if err != nil {
+			return nil, fmt.Errorf("scan %w", err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkPublished marks the event as accepted by broker, so it isn`t published again
+func (p *PgRepository) MarkPublished(ctx context.Context, eventID uuid.UUID) error {
+	_, err := p.pool.Exec(ctx, "UPDATE outbox SET status = $1, publishedtime = NOW(), lasterror = NULL WHERE eventid = $2",
+		string(model.EventPublished), eventID)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
+
+// RetryEvent records the reason of failed publishing and postpones the next attempt for delay
+func (p *PgRepository) RetryEvent(ctx context.Context, eventID uuid.UUID, reason string, delay time.Duration) error {
+	_, err := p.pool.Exec(ctx, "UPDATE outbox SET lasterror = $1, nextattempttime = NOW() + $2::interval WHERE eventid = $3",
+		reason, delay, eventID)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
+
+// DeadLetterEvent moves the event to dead letters with the reason of last failure, it isn`t published anymore
+func (p *PgRepository) DeadLetterEvent(ctx context.Context, eventID uuid.UUID, reason string) error {
+	_, err := p.pool.Exec(ctx, "UPDATE outbox SET status = $1, lasterror = $2 WHERE eventid = $3",
+		string(model.EventDead), reason, eventID)
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
+
+// insertEvent writes the event about change to outbox in the transaction of change,
+// so event is published if and only if the change is committed
+func insertEvent(ctx context.Context, tx pgx.Tx, topic string, change any) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("marshal %w", err)
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO outbox (eventid, topic, payload, status) VALUES ($1, $2, $3, $4)",
+		uuid.New(), topic, payload, string(model.EventPending))
+	if err != nil {
+		return fmt.Errorf("exec %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// claimEventOf claims pending events and returns the one about the operation
+func claimEventOf(t *testing.T, balanceID uuid.UUID) *model.Event {
+	events, err := pg.ClaimEvents(context.Background(), 10000, 0)
+	require.NoError(t, err)
+	for _, event := range events {
+		var operation model.Balance
+		require.NoError(t, json.Unmarshal(event.Payload, &operation))
+		if operation.BalanceID == balanceID {
+			return event
+		}
+	}
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	err = balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	event := claimEventOf(t, operation.BalanceID)
+	require.NotNil(t, event)
+	require.Equal(t, model.OperationTopic, event.Topic)
+	require.Equal(t, 1, event.Attempts)
+	var published model.Balance
+	require.NoError(t, json.Unmarshal(event.Payload, &published))
+	require.Equal(t, operation.ProfileID, published.ProfileID)
+	require.Equal(t, "100", published.Operation.String())
+	require.False(t, published.OperationTime.IsZero())
+
+	err = pg.RetryEvent(context.Background(), event.EventID, "broker is unavailable", time.Hour)
+	require.NoError(t, err)
+	require.Nil(t, claimEventOf(t, operation.BalanceID))
+	err = pg.RetryEvent(context.Background(), event.EventID, "broker is unavailable", 0)
+	require.NoError(t, err)
+	event = claimEventOf(t, operation.BalanceID)
+	require.NotNil(t, event)
+	require.Equal(t, 2, event.Attempts)
+	err = pg.MarkPublished(context.Background(), event.EventID)
+	require.NoError(t, err)
+	require.Nil(t, claimEventOf(t, operation.BalanceID))
+}
+
+func TestDeadLetterEvent(t *testing.T) {
+	operation := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: uuid.New(),
+		Operation: decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	err := balanceOperation(context.Background(), operation, operation.Entry())
+	require.NoError(t, err)
+	event := claimEventOf(t, operation.BalanceID)
+	require.NotNil(t, event)
+	err = pg.DeadLetterEvent(context.Background(), event.EventID, "broker is unavailable")
+	require.NoError(t, err)
+	var status, reason string
+	err = pg.pool.QueryRow(context.Background(), "SELECT status, lasterror FROM outbox WHERE eventid = $1", event.EventID).
+		Scan(&status, &reason)
+	require.NoError(t, err)
+	require.Equal(t, string(model.EventDead), status)
+	require.Equal(t, "broker is unavailable", reason)
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/logging"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// scheduleColumns are columns of schedules table in order of scanSchedule
+const scheduleColumns = "scheduleid, profileid, operation, currency, operationtype, description, expression, status, nextruntime, createdtime"
+
+// CreateSchedule records the schedule, replay of already recorded schedule with the same id returns it
+func (p *PgRepository) CreateSchedule(ctx context.Context, schedule *model.Schedule) error {
+	err := p.pool.QueryRow(ctx, `INSERT INTO schedules (scheduleid, profileid, operation, currency, operationtype, description, expression,
+		status, nextruntime) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (scheduleid) DO NOTHING RETURNING createdtime`,
+		schedule.ScheduleID, schedule.ProfileID, schedule.Operation, schedule.Currency, string(schedule.OperationType), schedule.Description,
+		schedule.Expression, string(schedule.Status), schedule.NextRunTime.UTC()).Scan(&schedule.CreatedTime)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("queryRow %w", err)
+	}
+	recorded, err := scanSchedule(p.pool.QueryRow(ctx, "SELECT "+scheduleColumns+" FROM schedules WHERE scheduleid = $1", schedule.ScheduleID))
+	if err != nil {
+		return fmt.Errorf("scanSchedule %w", err)
+	}
+	if recorded.ProfileID != schedule.ProfileID || !recorded.Operation.Equal(schedule.Operation) || recorded.Currency != schedule.Currency ||
+		recorded.OperationType != schedule.OperationType || recorded.Expression != schedule.Expression {
+		return berrors.New(berrors.IdempotencyConflict)
+	}
+	*schedule = *recorded
+	return nil
+}
+
+// CancelSchedule stops runs of the schedule and returns it, cancelling of cancelled schedule changes nothing
+func (p *PgRepository) CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error) {
+	schedule, err := scanSchedule(p.pool.QueryRow(ctx, "UPDATE schedules SET status = $1 WHERE scheduleid = $2 RETURNING "+scheduleColumns,
+		string(model.ScheduleCancelled), scheduleID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, berrors.New(berrors.ScheduleNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanSchedule %w", err)
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns schedules of profile in order of their creation with their last runs
+func (p *PgRepository) ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error) {
+	rows, err := p.pool.Query(ctx, "SELECT "+scheduleColumns+`, runid, runtime, runstatus, errorcode, reason, executedtime
+		FROM schedules LEFT JOIN LATERAL (SELECT runid, runtime, status AS runstatus, errorcode, reason, executedtime FROM schedule_runs
+		WHERE schedule_runs.scheduleid = schedules.scheduleid ORDER BY runtime DESC LIMIT 1) last ON true
+		WHERE profileid = $1 ORDER BY createdtime, scheduleid`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*model.Schedule
+
+	for rows.Next() {
+		var (
+			runID                   uuid.NullUUID
+			runTime, executedTime   *time.Time
+			runStatus, code, reason *string
+		)
+		schedule, err := scanSchedule(rows, &runID, &runTime, &runStatus, &code, &reason, &executedTime)
+		if err != nil {
+			return nil, fmt.Errorf("scanSchedule %w", err)
+		}
+		if runID.Valid {
+			schedule.LastRun = &model.ScheduleRun{
+				RunID:        runID.UUID,
+				ScheduleID:   schedule.ScheduleID,
+				RunTime:      *runTime,
+				Status:       model.RunStatus(*runStatus),
+				ErrorCode:    *code,
+				Reason:       *reason,
+				ExecutedTime: *executedTime,
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+
+	return schedules, nil
+}
+
+// DueSchedules returns active schedules whose next run is due at now, the most overdue come first
+func (p *PgRepository) DueSchedules(ctx context.Context, now time.Time, limit int) ([]*model.Schedule, error) {
+	rows, err := p.pool.Query(ctx, "SELECT "+scheduleColumns+` FROM schedules WHERE status = $1 AND nextruntime <= $2
+		ORDER BY nextruntime LIMIT $3`, string(model.ScheduleActive), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("query %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*model.Schedule
+
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanSchedule %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows %w", err)
+	}
+
+	return schedules, nil
+}
+
+// RecordRun records the result of run and moves the schedule to its next run with no failed attempts in one transaction,
+// replay of recorded run and run of cancelled schedule don`t move it
+func (p *PgRepository) RecordRun(ctx context.Context, run *model.ScheduleRun, nextRunTime time.Time) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `INSERT INTO schedule_runs (runid, scheduleid, runtime, status, errorcode, reason)
+			VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (runid) DO NOTHING RETURNING executedtime`,
+			run.RunID, run.ScheduleID, run.RunTime.UTC(), string(run.Status), run.ErrorCode, run.Reason).Scan(&run.ExecutedTime)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("queryRow %w", err)
+		}
+		_, err = tx.Exec(ctx, "UPDATE schedules SET nextruntime = $1, attempts = 0 WHERE scheduleid = $2 AND status = $3 AND nextruntime = $4",
+			nextRunTime.UTC(), run.ScheduleID, string(model.ScheduleActive), run.RunTime.UTC())
+		if err != nil {
+			return fmt.Errorf("exec %w", err)
+		}
+		return nil
+	})
+}
+
+// RecordAttempt counts the failed attempt of run of schedule at runTime and returns the number of its failed attempts,
+// it returns zero if the schedule was already moved to another run
+func (p *PgRepository) RecordAttempt(ctx context.Context, scheduleID uuid.UUID, runTime time.Time) (int, error) {
+	var attempts int
+	err := p.pool.QueryRow(ctx, "UPDATE schedules SET attempts = attempts + 1 WHERE scheduleid = $1 AND nextruntime = $2 RETURNING attempts",
+		scheduleID, runTime.UTC()).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("queryRow %w", err)
+	}
+	return attempts, nil
+}
+
+// Lead runs lead only if this instance holds the session lock of key, so one instance leads at a time,
+// it returns false without running lead if the lock is held by another instance. The lock is held by its own
+// connection which is checked every checkInterval, ctx of lead is cancelled if the connection and the lock with it are lost.
+func (p *PgRepository) Lead(ctx context.Context, key string, checkInterval time.Duration, lead func(ctx context.Context) error) (bool, error) {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire %w", err)
+	}
+	// the connection isn`t returned to pool, closing of it releases the lock
+	session := conn.Hijack()
+	defer session.Close(context.Background())
+	var locked bool
+	err = session.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtextextended($1, 0))", key).Scan(&locked)
+	if err != nil {
+		return false, fmt.Errorf("queryRow %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+	logger := logging.FromContext(ctx)
+	logger.Infof("lock %s is taken", key)
+	leadCtx, cancel := context.WithCancel(ctx)
+	checked := make(chan struct{})
+	go func() {
+		defer close(checked)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leadCtx.Done():
+				return
+			case <-ticker.C:
+				if err := session.Ping(leadCtx); err != nil {
+					logger.WithError(err).Warnf("lock %s is lost", key)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	err = lead(leadCtx)
+	cancel()
+	<-checked
+	return true, err
+}
+
+// scanSchedule scans scheduleColumns of the row into schedule and the rest columns into dest
+func scanSchedule(row pgx.Row, dest ...any) (*model.Schedule, error) {
+	var (
+		schedule              = &model.Schedule{}
+		operationType, status string
+	)
+	err := row.Scan(append([]any{&schedule.ScheduleID, &schedule.ProfileID, &schedule.Operation, &schedule.Currency, &operationType,
+		&schedule.Description, &schedule.Expression, &status, &schedule.NextRunTime, &schedule.CreatedTime}, dest...)...)
+	if err != nil {
+		return nil, err
+	}
+	schedule.OperationType, schedule.Status = model.OperationType(operationType), model.ScheduleStatus(status)
+	return schedule, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func createSchedule(t *testing.T, nextRunTime time.Time) *model.Schedule {
+	schedule := &model.Schedule{
+		ScheduleID:    uuid.New(),
+		ProfileID:     uuid.New(),
+		Operation:     decimal.NewFromInt(-100),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Withdrawal,
+		Expression:    "@every 1h",
+		Status:        model.ScheduleActive,
+		NextRunTime:   nextRunTime.UTC().Truncate(time.Second),
+	}
+	err := pg.CreateSchedule(context.Background(), schedule)
+	require.NoError(t, err)
+	return schedule
+}
+
+func TestCreateSchedule(t *testing.T) {
+	schedule := createSchedule(t, time.Now().Add(time.Hour))
+	replayed := *schedule
+	replayed.NextRunTime = time.Now().Add(2 * time.Hour)
+	err := pg.CreateSchedule(context.Background(), &replayed)
+	require.NoError(t, err)
+	require.True(t, schedule.NextRunTime.Equal(replayed.NextRunTime))
+	conflicting := *schedule
+	conflicting.Expression = "@daily"
+	err = pg.CreateSchedule(context.Background(), &conflicting)
+	require.ErrorIs(t, err, berrors.New(berrors.IdempotencyConflict))
+}
+
+func TestRecordRun(t *testing.T) {
+	schedule := createSchedule(t, time.Now().Add(-time.Minute))
+	due, err := pg.DueSchedules(context.Background(), time.Now(), 1000)
+	require.NoError(t, err)
+	require.Contains(t, scheduleIDs(due), schedule.ScheduleID)
+	run := &model.ScheduleRun{
+		RunID:      schedule.RunID(),
+		ScheduleID: schedule.ScheduleID,
+		RunTime:    schedule.NextRunTime,
+		Status:     model.RunFailed,
+		ErrorCode:  berrors.NotEnoughMoney,
+		Reason:     berrors.NotEnoughMoney,
+	}
+	next := schedule.NextRunTime.Add(time.Hour)
+	err = pg.RecordRun(context.Background(), run, next)
+	require.NoError(t, err)
+	err = pg.RecordRun(context.Background(), run, next.Add(time.Hour))
+	require.NoError(t, err)
+	due, err = pg.DueSchedules(context.Background(), time.Now(), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, scheduleIDs(due), schedule.ScheduleID)
+
+	schedules, err := pg.ListSchedules(context.Background(), schedule.ProfileID)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	require.True(t, next.Equal(schedules[0].NextRunTime))
+	require.NotNil(t, schedules[0].LastRun)
+	require.Equal(t, model.RunFailed, schedules[0].LastRun.Status)
+	require.Equal(t, berrors.NotEnoughMoney, schedules[0].LastRun.ErrorCode)
+}
+
+func TestRecordAttempt(t *testing.T) {
+	schedule := createSchedule(t, time.Now().Add(-time.Minute))
+	for expected := 1; expected <= 2; expected++ {
+		attempts, err := pg.RecordAttempt(context.Background(), schedule.ScheduleID, schedule.NextRunTime)
+		require.NoError(t, err)
+		require.Equal(t, expected, attempts)
+	}
+	next := schedule.NextRunTime.Add(time.Hour)
+	err := pg.RecordRun(context.Background(), &model.ScheduleRun{
+		RunID:      schedule.RunID(),
+		ScheduleID: schedule.ScheduleID,
+		RunTime:    schedule.NextRunTime,
+		Status:     model.RunSucceeded,
+	}, next)
+	require.NoError(t, err)
+	attempts, err := pg.RecordAttempt(context.Background(), schedule.ScheduleID, schedule.NextRunTime)
+	require.NoError(t, err)
+	require.Zero(t, attempts)
+	attempts, err = pg.RecordAttempt(context.Background(), schedule.ScheduleID, next)
+	require.NoError(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestCancelSchedule(t *testing.T) {
+	schedule := createSchedule(t, time.Now().Add(-time.Minute))
+	cancelled, err := pg.CancelSchedule(context.Background(), schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleCancelled, cancelled.Status)
+	due, err := pg.DueSchedules(context.Background(), time.Now(), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, scheduleIDs(due), schedule.ScheduleID)
+	schedules, err := pg.ListSchedules(context.Background(), schedule.ProfileID)
+	require.NoError(t, err)
+	require.Nil(t, schedules[0].LastRun)
+	_, err = pg.CancelSchedule(context.Background(), uuid.New())
+	require.ErrorIs(t, err, berrors.New(berrors.ScheduleNotFound))
+}
+
+func TestLead(t *testing.T) {
+	key := uuid.NewString()
+	leading := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		led, err := pg.Lead(context.Background(), key, time.Second, func(ctx context.Context) error {
+			close(leading)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+		require.NoError(t, err)
+		require.True(t, led)
+	}()
+	<-leading
+	led, err := pg.Lead(context.Background(), key, time.Second, func(ctx context.Context) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.False(t, led)
+	<-done
+	led, err = pg.Lead(context.Background(), key, time.Second, func(ctx context.Context) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, led)
+}
+
+func scheduleIDs(schedules []*model.Schedule) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(schedules))
+	for _, schedule := range schedules {
+		ids = append(ids, schedule.ScheduleID)
+	}
+	return ids
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+)
+
+// FreezeAccount is a method of BalanceService that forbids withdrawals of profile, deposits are forbidden too
+// if freezeDeposits is true
+func (b *BalanceService) FreezeAccount(ctx context.Context, change *model.AccountChange, freezeDeposits bool) (*model.AccountState, error) {
+	state, err := b.bRep.ChangeAccount(ctx, change, func(state *model.AccountState) error {
+		if state.Status == model.AccountClosed {
+			return berrors.New(berrors.AccountClosed)
+		}
+		state.Status, state.FreezeDeposits = model.AccountFrozen, freezeDeposits
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("changeAccount %w", err)
+	}
+	return state, nil
+}
+
+// UnfreezeAccount is a method of BalanceService that allows all operations of frozen profile again
+func (b *BalanceService) UnfreezeAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error) {
+	state, err := b.bRep.ChangeAccount(ctx, change, func(state *model.AccountState) error {
+		if state.Status == model.AccountClosed {
+			return berrors.New(berrors.AccountClosed)
+		}
+		state.Status, state.FreezeDeposits = model.AccountActive, false
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("changeAccount %w", err)
+	}
+	return state, nil
+}
+
+// CloseAccount is a method of BalanceService that forbids all operations of profile for good
+func (b *BalanceService) CloseAccount(ctx context.Context, change *model.AccountChange) (*model.AccountState, error) {
+	state, err := b.bRep.ChangeAccount(ctx, change, func(state *model.AccountState) error {
+		state.Status, state.FreezeDeposits = model.AccountClosed, false
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("changeAccount %w", err)
+	}
+	return state, nil
+}
+
+// accountAllows returns the check of state of profile for the operation
+func accountAllows(balance *model.Balance) func(state *model.AccountState) error {
+	return func(state *model.AccountState) error {
+		if state.Allows(balance) {
+			return nil
+		}
+		if state.Status == model.AccountClosed {
+			return berrors.New(berrors.AccountClosed)
+		}
+		return berrors.New(berrors.AccountFrozen).WithMetadata(berrors.OperationTypeKey, string(balance.OperationType))
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountAllows(t *testing.T) {
+	deposit := &model.Balance{Operation: decimal.NewFromInt(10)}
+	withdraw := &model.Balance{Operation: decimal.NewFromInt(-10)}
+	testCases := []struct {
+		name  string
+		state *model.AccountState
+		op    *model.Balance
+		code  string
+	}{
+		{name: "active withdraw", state: &model.AccountState{Status: model.AccountActive}, op: withdraw},
+		{name: "frozen deposit", state: &model.AccountState{Status: model.AccountFrozen}, op: deposit},
+		{name: "frozen withdraw", state: &model.AccountState{Status: model.AccountFrozen}, op: withdraw, code: berrors.AccountFrozen},
+		{name: "frozen deposits", state: &model.AccountState{Status: model.AccountFrozen, FreezeDeposits: true}, op: deposit,
+			code: berrors.AccountFrozen},
+		{name: "closed deposit", state: &model.AccountState{Status: model.AccountClosed}, op: deposit, code: berrors.AccountClosed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := accountAllows(tc.op)(tc.state)
+			if tc.code == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, berrors.New(tc.code))
+		})
+	}
+}
+
+func TestFrozenBalanceOperation(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Account(&model.AccountState{Status: model.AccountFrozen})
+		}).Once()
+	err := srv.BalanceOperation(context.Background(), &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromInt(-1),
+		Currency:  model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+	rep.AssertExpectations(t)
+}
+
+func TestFrozenSender(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	frozen := &model.AccountState{ProfileID: testBalance.ProfileID, Status: model.AccountFrozen}
+
+	rep.On("Transfer", mock.Anything, mock.AnythingOfType("*model.Transfer"), mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(func(_ context.Context, transfer *model.Transfer, _ *model.JournalEntry, checks func(*model.Balance) *model.Checks) error {
+			require.NoError(t, checks(transfer.Credit()).Account(frozen))
+			return checks(transfer.Debit()).Account(frozen)
+		}).Once()
+	err := srv.Transfer(context.Background(), &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(10),
+		Currency:      model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+
+	rep.On("Hold", mock.Anything, mock.AnythingOfType("*model.Hold"), time.Hour, mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Hold, _ time.Duration, checks *model.Checks) error {
+			return checks.Account(frozen)
+		}).Once()
+	err = srv.Hold(context.Background(), &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Amount:    decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+
+	hold := &model.Hold{HoldID: uuid.New(), ProfileID: testBalance.ProfileID, Captured: decimal.NewFromInt(10), Currency: model.DefaultCurrency}
+	rep.On("CaptureHold", mock.Anything, hold.HoldID, decimal.Zero, mock.Anything).
+		Return(nil, func(_ context.Context, _ uuid.UUID, _ decimal.Decimal, checks func(*model.Balance) *model.Checks) error {
+			return checks(hold.Capture()).Account(frozen)
+		}).Once()
+	_, err = srv.CaptureHold(context.Background(), hold.HoldID, decimal.Zero)
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+
+	deposit := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     testBalance.ProfileID,
+		Operation:     decimal.NewFromInt(10),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Deposit,
+	}
+	rep.On("GetOperation", mock.Anything, deposit.BalanceID).Return(deposit, nil).Once()
+	rep.On("ReverseOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Account(frozen)
+		}).Once()
+	_, err = srv.ReverseOperation(context.Background(), deposit.BalanceID, uuid.New(), "")
+	require.ErrorIs(t, err, berrors.New(berrors.AccountFrozen))
+	rep.AssertExpectations(t)
+}
+
+func TestChangeAccount(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	change := &model.AccountChange{ProfileID: testBalance.ProfileID, Actor: "compliance", Reason: "investigation"}
+	changed := func(status model.AccountStatus) (any, any) {
+		state := &model.AccountState{ProfileID: change.ProfileID, Status: status}
+		return func(context.Context, *model.AccountChange, func(*model.AccountState) error) *model.AccountState {
+				return state
+			}, func(_ context.Context, _ *model.AccountChange, apply func(*model.AccountState) error) error {
+				return apply(state)
+			}
+	}
+	rep.On("ChangeAccount", mock.Anything, change, mock.Anything).Return(changed(model.AccountActive)).Once()
+	state, err := srv.FreezeAccount(context.Background(), change, true)
+	require.NoError(t, err)
+	require.Equal(t, model.AccountFrozen, state.Status)
+	require.True(t, state.FreezeDeposits)
+	rep.On("ChangeAccount", mock.Anything, change, mock.Anything).Return(changed(model.AccountFrozen)).Once()
+	state, err = srv.UnfreezeAccount(context.Background(), change)
+	require.NoError(t, err)
+	require.Equal(t, model.AccountActive, state.Status)
+	require.False(t, state.FreezeDeposits)
+	rep.On("ChangeAccount", mock.Anything, change, mock.Anything).Return(changed(model.AccountClosed)).Once()
+	_, err = srv.FreezeAccount(context.Background(), change, false)
+	require.ErrorIs(t, err, berrors.New(berrors.AccountClosed))
+	rep.AssertExpectations(t)
+}
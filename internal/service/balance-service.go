@@ -4,57 +4,405 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/hub"
+	"github.com/artnikel/BalanceService/internal/metrics"
 	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 // BalanceRepository is interface with methods for balance operations
 type BalanceRepository interface {
-	BalanceOperation(ctx context.Context, balance *model.Balance) error
-	GetBalance(ctx context.Context, profileID uuid.UUID) (float64, error)
+	CheckedBalanceOperation(ctx context.Context, balance *model.Balance, entry *model.JournalEntry, checks *model.Checks) error
+	GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error)
+	GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error)
+	ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, error)
+	Transfer(ctx context.Context, transfer *model.Transfer, entry *model.JournalEntry, checks func(operation *model.Balance) *model.Checks) error
+	Reconcile(ctx context.Context) ([]*model.Drift, error)
+	Hold(ctx context.Context, hold *model.Hold, ttl time.Duration, checks *model.Checks) error
+	GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal, checks func(capture *model.Balance) *model.Checks) (*model.Balance, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) error
+	GetOperation(ctx context.Context, balanceID uuid.UUID) (*model.Balance, error)
+	ReverseOperation(ctx context.Context, reversal *model.Balance, entry *model.JournalEntry, checks *model.Checks) error
+	ExpireHolds(ctx context.Context) (int64, error)
+	GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error)
+	SetLimit(ctx context.Context, limit *model.Limit) error
+	SetCreditLimit(ctx context.Context, line *model.CreditLine) error
+	BatchBalanceOperations(ctx context.Context, batch []*model.BatchOperation, atomic bool) error
+	CreateSchedule(ctx context.Context, schedule *model.Schedule) error
+	CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error)
+	ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error)
+	DueSchedules(ctx context.Context, now time.Time, limit int) ([]*model.Schedule, error)
+	RecordRun(ctx context.Context, run *model.ScheduleRun, nextRunTime time.Time) error
+	RecordAttempt(ctx context.Context, scheduleID uuid.UUID, runTime time.Time) (int, error)
+	ChangeAccount(ctx context.Context, change *model.AccountChange, apply func(state *model.AccountState) error) (*model.AccountState, error)
+	ListenBalanceChanges(ctx context.Context, listening func(), handle func(change *model.BalanceChange)) error
 }
 
-// BalanceService contains BalanceRepository interface
+// BalanceService contains BalanceRepository interface, time to live of holds and hub of balance watchers
 type BalanceService struct {
-	bRep BalanceRepository
+	bRep     BalanceRepository
+	holdTTL  time.Duration
+	watchers *hub.Hub
 }
 
-// NewBalanceService accepts BalanceRepository object and returnes an object of type *BalanceService
-func NewBalanceService(bRep BalanceRepository) *BalanceService {
-	return &BalanceService{bRep: bRep}
+// NewBalanceService accepts BalanceRepository object and time to live of holds and returnes an object of type *BalanceService
+func NewBalanceService(bRep BalanceRepository, holdTTL time.Duration) *BalanceService {
+	return &BalanceService{bRep: bRep, holdTTL: holdTTL, watchers: hub.New()}
 }
 
 // BalanceOperation is a method of BalanceService that calls  method of Repository
 func (b *BalanceService) BalanceOperation(ctx context.Context, balance *model.Balance) error {
+	ctx, span := tracing.Start(ctx, "service.BalanceOperation", tracing.Operation(balance)...)
+	defer span.End()
+	entry, checks, err := prepareOperation(balance)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("prepareOperation %w", err)
+	}
+	err = b.bRep.CheckedBalanceOperation(ctx, balance, entry, checks)
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		return fmt.Errorf("checkedBalanceOperation %w", err)
+	}
+	metrics.ObserveOperation(balance)
+	return nil
+}
+
+// prepareOperation sets the default type of operation, checks its type and returns its journal entry
+// and checks of state of profile
+func prepareOperation(balance *model.Balance) (*model.JournalEntry, *model.Checks, error) {
+	if balance.Operation.IsZero() {
+		return nil, nil, berrors.New(berrors.ZeroAmount)
+	}
+	if balance.OperationType == "" {
+		balance.OperationType = model.DefaultOperationType(balance.Operation)
+	}
+	err := checkOperationType(balance)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checkOperationType %w", err)
+	}
+	entry := balance.Entry()
+	err = balanced(entry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("balanced %w", err)
+	}
+	return entry, operationChecks(balance), nil
+}
+
+// operationChecks returns checks of state of profile and its limits for the operation,
+// withdrawal is checked against the available balance too
+func operationChecks(balance *model.Balance) *model.Checks {
+	checks := &model.Checks{
+		Account: accountAllows(balance),
+		Limits:  withinLimits(balance.Operation.Abs(), balance.Currency),
+	}
 	if balance.Operation.IsNegative() {
-		money, err := b.GetBalance(ctx, balance.ProfileID)
+		checks.Funds = enoughMoney(balance.Operation.Abs(), balance.Currency)
+	}
+	return checks
+}
+
+// GetBalance is a method of BalanceService that calls  method of Repository, it returns total and available balance
+func (b *BalanceService) GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error) {
+	ctx, span := tracing.Start(ctx, "service.GetBalance", tracing.ProfileIDKey.String(profileID.String()))
+	defer span.End()
+	funds, err := b.bRep.GetBalance(ctx, profileID, currency)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("getBalance %w", err)
+	}
+	return funds, nil
+}
+
+// GetBalanceAt is a method of BalanceService that calls method of Repository, it returns total balance of profile
+// at the moment, available balance and credit limit in the past aren`t known
+func (b *BalanceService) GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error) {
+	ctx, span := tracing.Start(ctx, "service.GetBalanceAt", tracing.ProfileIDKey.String(profileID.String()))
+	defer span.End()
+	funds, err := b.bRep.GetBalanceAt(ctx, profileID, currency, asOf)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("getBalanceAt %w", err)
+	}
+	return funds, nil
+}
+
+// ListOperations is a method of BalanceService that calls method of Repository,
+// it returns the cursor of the next page if profile has more operations
+func (b *BalanceService) ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, *model.Cursor, error) {
+	page := *filter
+	page.Limit++
+	operations, err := b.bRep.ListOperations(ctx, &page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listOperations %w", err)
+	}
+	if len(operations) <= filter.Limit {
+		return operations, nil, nil
+	}
+	operations = operations[:filter.Limit]
+	last := operations[len(operations)-1]
+	return operations, &model.Cursor{OperationTime: last.OperationTime, BalanceID: last.BalanceID}, nil
+}
+
+// Transfer is a method of BalanceService that moves money from one profile to another if source profile has enough money,
+// states of both profiles allow their operations and the operations are within limits of profiles
+func (b *BalanceService) Transfer(ctx context.Context, transfer *model.Transfer) error {
+	ctx, span := tracing.Start(ctx, "service.Transfer", tracing.ProfileIDKey.String(transfer.FromProfileID.String()))
+	defer span.End()
+	if transfer.FromProfileID == transfer.ToProfileID {
+		err := berrors.New(berrors.SelfTransfer)
+		tracing.RecordError(span, err)
+		return err
+	}
+	if transfer.Amount.IsZero() {
+		err := berrors.New(berrors.ZeroAmount)
+		tracing.RecordError(span, err)
+		return err
+	}
+	if transfer.Amount.IsNegative() {
+		err := berrors.New(berrors.InvalidAmount).WithMetadata(berrors.RequiredKey, transfer.Amount.String())
+		tracing.RecordError(span, err)
+		return err
+	}
+	entry := transfer.Entry()
+	err := balanced(entry)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("balanced %w", err)
+	}
+	err = b.bRep.Transfer(ctx, transfer, entry, operationChecks)
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		return fmt.Errorf("transfer %w", err)
+	}
+	return nil
+}
+
+// Reconcile is a method of BalanceService that calls method of Repository and returns drifted balances
+func (b *BalanceService) Reconcile(ctx context.Context) ([]*model.Drift, error) {
+	drifts, err := b.bRep.Reconcile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile %w", err)
+	}
+	return drifts, nil
+}
+
+// Hold is a method of BalanceService that reserves money of profile if its available balance is enough,
+// its state allows withdrawals and the amount is within its withdrawal limits
+func (b *BalanceService) Hold(ctx context.Context, hold *model.Hold) error {
+	ctx, span := tracing.Start(ctx, "service.Hold", tracing.ProfileIDKey.String(hold.ProfileID.String()))
+	defer span.End()
+	if hold.Amount.IsZero() {
+		err := berrors.New(berrors.ZeroAmount)
+		tracing.RecordError(span, err)
+		return err
+	}
+	if hold.Amount.IsNegative() {
+		err := berrors.New(berrors.InvalidAmount).WithMetadata(berrors.RequiredKey, hold.Amount.String())
+		tracing.RecordError(span, err)
+		return err
+	}
+	err := b.bRep.Hold(ctx, hold, b.holdTTL, operationChecks(hold.Reservation()))
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		return fmt.Errorf("hold %w", err)
+	}
+	return nil
+}
+
+// GetHold is a method of BalanceService that calls method of Repository
+func (b *BalanceService) GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error) {
+	hold, err := b.bRep.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("getHold %w", err)
+	}
+	return hold, nil
+}
+
+// CaptureHold is a method of BalanceService that withdraws the amount of hold if state of profile allows withdrawals
+// and the amount is within its withdrawal limits, zero amount captures the whole hold.
+// The available balance isn`t checked, since the amount is already reserved.
+func (b *BalanceService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*model.Balance, error) {
+	ctx, span := tracing.Start(ctx, "service.CaptureHold", tracing.HoldIDKey.String(holdID.String()))
+	defer span.End()
+	if amount.IsNegative() {
+		err := berrors.New(berrors.InvalidAmount).WithMetadata(berrors.RequiredKey, amount.String())
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	operation, err := b.bRep.CaptureHold(ctx, holdID, amount, func(capture *model.Balance) *model.Checks {
+		return &model.Checks{
+			Account: accountAllows(capture),
+			Limits:  withinLimits(capture.Operation.Abs(), capture.Currency),
+		}
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		return nil, fmt.Errorf("captureHold %w", err)
+	}
+	metrics.ObserveOperation(operation)
+	return operation, nil
+}
+
+// ReverseOperation is a method of BalanceService that records the operation with id reversalID which compensates
+// the operation with id balanceID if state of profile allows it, reversal which takes money back is checked as a withdrawal
+func (b *BalanceService) ReverseOperation(ctx context.Context, balanceID, reversalID uuid.UUID, description string) (*model.Balance, error) {
+	ctx, span := tracing.Start(ctx, "service.ReverseOperation", tracing.OperationIDKey.String(balanceID.String()))
+	defer span.End()
+	original, err := b.bRep.GetOperation(ctx, balanceID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("getOperation %w", err)
+	}
+	if original.ReversalOf.Valid {
+		err = berrors.New(berrors.DuplicateOperation).WithMetadata(berrors.OperationTypeKey, string(original.OperationType))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if original.TransferID.Valid {
+		err = berrors.New(berrors.InvalidOperationType).WithMetadata(berrors.OperationTypeKey, string(original.OperationType))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	reversal := original.Reverse(reversalID, description)
+	entry := original.Entry().Reverse(reversal.BalanceID)
+	err = balanced(entry)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("balanced %w", err)
+	}
+	checks := &model.Checks{Account: accountAllows(reversal)}
+	if reversal.Operation.IsNegative() {
+		checks.Funds = enoughMoney(reversal.Operation.Abs(), reversal.Currency)
+	}
+	err = b.bRep.ReverseOperation(ctx, reversal, entry, checks)
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		return nil, fmt.Errorf("reverseOperation %w", err)
+	}
+	metrics.ObserveOperation(reversal)
+	return reversal, nil
+}
+
+// ReleaseHold is a method of BalanceService that calls method of Repository
+func (b *BalanceService) ReleaseHold(ctx context.Context, holdID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "service.ReleaseHold", tracing.HoldIDKey.String(holdID.String()))
+	defer span.End()
+	err := b.bRep.ReleaseHold(ctx, holdID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("releaseHold %w", err)
+	}
+	return nil
+}
+
+// ExpireHolds is a method of BalanceService that calls method of Repository and returns the number of expired holds
+func (b *BalanceService) ExpireHolds(ctx context.Context) (int64, error) {
+	expired, err := b.bRep.ExpireHolds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("expireHolds %w", err)
+	}
+	return expired, nil
+}
+
+// WatchBalance sends the current balance of profile in the currency and then the new balance after every its change
+// until the context is done or send fails
+func (b *BalanceService) WatchBalance(ctx context.Context, profileID uuid.UUID, currency string, send func(funds *model.Funds) error) error {
+	signals, unsubscribe := b.watchers.Subscribe(profileID, currency)
+	defer unsubscribe()
+	var sent *model.Funds
+	for {
+		funds, err := b.bRep.GetBalance(ctx, profileID, currency)
 		if err != nil {
 			return fmt.Errorf("getBalance %w", err)
 		}
-		if decimal.NewFromFloat(money).Cmp(balance.Operation.Abs()) == 1 {
-			err = b.bRep.BalanceOperation(ctx, balance)
+		if sent == nil || !sent.Total.Equal(funds.Total) || !sent.Available.Equal(funds.Available) {
+			err = send(funds)
 			if err != nil {
-				return fmt.Errorf("balanceOperation %w", err)
+				return fmt.Errorf("send %w", err)
 			}
-			return nil
+			sent = funds
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signals:
 		}
-		return berrors.New(berrors.NotEnoughMoney)
 	}
-	err := b.bRep.BalanceOperation(ctx, balance)
+}
+
+// ListenBalanceChanges passes changes of balances committed by all replicas of service to watchers of this replica
+// until the context is done or listening fails. Watchers read balances again once listening starts,
+// because changes could be missed while it was stopped.
+func (b *BalanceService) ListenBalanceChanges(ctx context.Context) error {
+	err := b.bRep.ListenBalanceChanges(ctx, b.watchers.Broadcast, b.watchers.Publish)
 	if err != nil {
-		return fmt.Errorf("balanceOperation %w", err)
+		return fmt.Errorf("listenBalanceChanges %w", err)
 	}
 	return nil
 }
 
-// GetBalance is a method of BalanceService that calls  method of Repository
-func (b *BalanceService) GetBalance(ctx context.Context, profileID uuid.UUID) (float64, error) {
-	money, err := b.bRep.GetBalance(ctx, profileID)
-	if err != nil {
-		return 0, fmt.Errorf("getBalance %w", err)
+// checkOperationType checks that the sign of amount matches the type of operation, transfers, captures of holds
+// and reversals are recorded only by their own methods
+func checkOperationType(balance *model.Balance) error {
+	var valid bool
+	switch balance.OperationType {
+	case model.Deposit, model.Bonus:
+		valid = balance.Operation.IsPositive()
+	case model.Withdrawal, model.Fee:
+		valid = balance.Operation.IsNegative()
+	case model.TradeSettlement, model.Refund:
+		valid = true
+	case model.TransferOperation, model.HoldCapture, model.Reversal:
+		valid = false
+	}
+	if !valid {
+		return berrors.New(berrors.InvalidOperationType).WithMetadata(berrors.OperationTypeKey, string(balance.OperationType))
+	}
+	return nil
+}
+
+// balanced checks the invariant of double-entry ledger: entry moves money between at least two accounts
+// in one currency and amounts of its postings sum to zero
+func balanced(entry *model.JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return berrors.New(berrors.UnbalancedEntry)
+	}
+	sum := decimal.Zero
+	for _, posting := range entry.Postings {
+		if posting.Account.Currency != entry.Postings[0].Account.Currency {
+			return berrors.New(berrors.CurrencyMismatch).WithMetadata(berrors.CurrencyKey, posting.Account.Currency)
+		}
+		sum = sum.Add(posting.Amount)
+	}
+	if !sum.IsZero() {
+		return berrors.New(berrors.UnbalancedEntry).WithMetadata(berrors.RequiredKey, sum.String())
+	}
+	return nil
+}
+
+// enoughMoney returns the check that profile can take the amount, balance with credit limit must cover it,
+// so the whole balance can be withdrawn
+func enoughMoney(amount decimal.Decimal, currency string) func(funds *model.Funds) error {
+	return func(funds *model.Funds) error {
+		if funds.Spendable().GreaterThanOrEqual(amount) {
+			return nil
+		}
+		return berrors.New(berrors.NotEnoughMoney).
+			WithMetadata(berrors.RequiredKey, amount.String()).
+			WithMetadata(berrors.AvailableKey, funds.Available.String()).
+			WithMetadata(berrors.CreditLimitKey, funds.CreditLimit.String()).
+			WithMetadata(berrors.CurrencyKey, currency)
 	}
-	return money, nil
 }
@@ -3,13 +3,21 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
+	berrors "github.com/artnikel/BalanceService/internal/errors"
 	"github.com/artnikel/BalanceService/internal/model"
 	"github.com/artnikel/BalanceService/internal/service/mocks"
+	"github.com/artnikel/BalanceService/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -17,16 +25,382 @@ var (
 		BalanceID: uuid.New(),
 		ProfileID: uuid.New(),
 		Operation: decimal.NewFromFloat(200.5),
+		Currency:  model.DefaultCurrency,
 	}
 )
 
 func TestBalanceOperation(t *testing.T) {
 	rep := new(mocks.BalanceRepository)
-	srv := NewBalanceService(rep)
-	rep.On("BalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance")).Return(nil).Once()
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Run(func(args mock.Arguments) {
+			require.Nil(t, args.Get(3).(*model.Checks).Funds)
+		}).Return(nil).Once()
 	err := srv.BalanceOperation(context.Background(), testBalance)
 	require.NoError(t, err)
 	rep.AssertExpectations(t)
 }
 
+func TestWithdrawOperation(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	withdraw := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromFloat(-100.5),
+		Currency:  model.DefaultCurrency,
+	}
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Run(func(args mock.Arguments) {
+			checks := args.Get(3).(*model.Checks)
+			require.NoError(t, checks.Funds(&model.Funds{Available: testBalance.Operation}))
+		}).Return(nil).Once()
+	err := srv.BalanceOperation(context.Background(), withdraw)
+	require.NoError(t, err)
+	rep.AssertExpectations(t)
+}
+
+func TestWithdrawOperationNotEnoughMoney(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	withdraw := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromFloat(-300.5),
+		Currency:  model.DefaultCurrency,
+	}
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Funds(&model.Funds{Available: testBalance.Operation})
+		}).Once()
+	err := srv.BalanceOperation(context.Background(), withdraw)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, "300.5", e.Metadata[berrors.RequiredKey])
+	require.Equal(t, "200.5", e.Metadata[berrors.AvailableKey])
+	require.Equal(t, model.DefaultCurrency, e.Metadata[berrors.CurrencyKey])
+	rep.AssertExpectations(t)
+}
+
+func TestBalanceOperationSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(provider)
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	withdraw := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromFloat(-300.5),
+		Currency:  model.DefaultCurrency,
+	}
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Run(func(args mock.Arguments) {
+			require.True(t, trace.SpanContextFromContext(args.Get(0).(context.Context)).IsValid())
+		}).Return(berrors.New(berrors.NotEnoughMoney)).Once()
+	err := srv.BalanceOperation(context.Background(), withdraw)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "service.BalanceOperation", spans[0].Name)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Contains(t, spans[0].Attributes, tracing.ProfileIDKey.String(withdraw.ProfileID.String()))
+	require.Contains(t, spans[0].Attributes, tracing.SignKey.Int(-1))
+	rep.AssertExpectations(t)
+}
 
+func TestZeroBalanceOperation(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	err := srv.BalanceOperation(context.Background(), &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.Zero,
+		Currency:  model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.ZeroAmount))
+	rep.AssertNotCalled(t, "CheckedBalanceOperation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOperationType(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	deposit := &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	}
+	rep.On("CheckedBalanceOperation", mock.Anything, deposit, mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(nil).Once()
+	err := srv.BalanceOperation(context.Background(), deposit)
+	require.NoError(t, err)
+	require.Equal(t, model.Deposit, deposit.OperationType)
+	for _, operation := range []*model.Balance{
+		{ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(-10), OperationType: model.Bonus},
+		{ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(10), OperationType: model.Fee},
+		{ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(10), OperationType: model.TransferOperation},
+	} {
+		err = srv.BalanceOperation(context.Background(), operation)
+		require.ErrorIs(t, err, berrors.New(berrors.InvalidOperationType))
+	}
+	rep.AssertExpectations(t)
+}
+
+func TestReverseOperation(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	deposit := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     testBalance.ProfileID,
+		Operation:     decimal.NewFromInt(300),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Deposit,
+	}
+	reversalID := uuid.New()
+	rep.On("GetOperation", mock.Anything, deposit.BalanceID).Return(deposit, nil).Once()
+	rep.On("ReverseOperation", mock.Anything, mock.MatchedBy(func(reversal *model.Balance) bool {
+		return reversal.BalanceID == reversalID && reversal.ReversalOf.UUID == deposit.BalanceID && reversal.Operation.String() == "-300"
+	}), mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Funds(&model.Funds{Available: decimal.NewFromInt(100)})
+		}).Once()
+	_, err := srv.ReverseOperation(context.Background(), deposit.BalanceID, reversalID, "")
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+
+	withdraw := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     testBalance.ProfileID,
+		Operation:     decimal.NewFromInt(-300),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Withdrawal,
+	}
+	rep.On("GetOperation", mock.Anything, withdraw.BalanceID).Return(withdraw, nil).Once()
+	rep.On("ReverseOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.Anything).
+		Run(func(args mock.Arguments) {
+			require.Nil(t, args.Get(3).(*model.Checks).Funds)
+		}).Return(nil).Once()
+	reversal, err := srv.ReverseOperation(context.Background(), withdraw.BalanceID, reversalID, "mistake")
+	require.NoError(t, err)
+	require.Equal(t, "300", reversal.Operation.String())
+	require.Equal(t, model.Reversal, reversal.OperationType)
+	require.Equal(t, "mistake", reversal.Description)
+
+	rep.On("GetOperation", mock.Anything, reversal.BalanceID).Return(reversal, nil).Once()
+	_, err = srv.ReverseOperation(context.Background(), reversal.BalanceID, uuid.New(), "")
+	require.ErrorIs(t, err, berrors.New(berrors.DuplicateOperation))
+
+	fee := &model.Balance{
+		BalanceID:     uuid.New(),
+		ProfileID:     testBalance.ProfileID,
+		Operation:     decimal.NewFromInt(-5),
+		Currency:      model.DefaultCurrency,
+		OperationType: model.Fee,
+	}
+	feeReversalID := uuid.New()
+	rep.On("GetOperation", mock.Anything, fee.BalanceID).Return(fee, nil).Once()
+	rep.On("ReverseOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.MatchedBy(func(entry *model.JournalEntry) bool {
+		return entry.EntryID == feeReversalID &&
+			entry.Postings[0].Account == model.Wallet(fee.ProfileID, fee.Currency) && entry.Postings[0].Amount.String() == "5" &&
+			entry.Postings[1].Account == model.SystemAccount(model.FeeAccount, fee.Currency) && entry.Postings[1].Amount.String() == "-5"
+	}), mock.Anything).Return(nil).Once()
+	_, err = srv.ReverseOperation(context.Background(), fee.BalanceID, feeReversalID, "")
+	require.NoError(t, err)
+	transferLeg := &model.Balance{
+		BalanceID:  uuid.New(),
+		ProfileID:  testBalance.ProfileID,
+		Operation:  decimal.NewFromInt(-10),
+		Currency:   model.DefaultCurrency,
+		TransferID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+	rep.On("GetOperation", mock.Anything, transferLeg.BalanceID).Return(transferLeg, nil).Once()
+	_, err = srv.ReverseOperation(context.Background(), transferLeg.BalanceID, uuid.New(), "")
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidOperationType))
+	rep.AssertExpectations(t)
+}
+
+func TestListOperations(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	operations := []*model.Balance{
+		{BalanceID: uuid.New(), ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(10), OperationTime: time.Now()},
+		{BalanceID: uuid.New(), ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(20), OperationTime: time.Now()},
+		{BalanceID: uuid.New(), ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(30), OperationTime: time.Now()},
+	}
+	rep.On("ListOperations", mock.Anything, mock.MatchedBy(func(filter *model.OperationFilter) bool {
+		return filter.Limit == 3
+	})).Return(operations, nil).Once()
+	page, cursor, err := srv.ListOperations(context.Background(), &model.OperationFilter{ProfileID: testBalance.ProfileID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, operations[1].BalanceID, cursor.BalanceID)
+	require.Equal(t, operations[1].OperationTime, cursor.OperationTime)
+	rep.On("ListOperations", mock.Anything, mock.AnythingOfType("*model.OperationFilter")).Return(operations[2:], nil).Once()
+	page, cursor, err = srv.ListOperations(context.Background(), &model.OperationFilter{ProfileID: testBalance.ProfileID, Limit: 2, After: cursor})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Nil(t, cursor)
+	rep.AssertExpectations(t)
+}
+
+func TestTransfer(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(100),
+		Currency:      model.DefaultCurrency,
+	}
+	rep.On("Transfer", mock.Anything, transfer, mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(func(_ context.Context, transfer *model.Transfer, _ *model.JournalEntry, checks func(*model.Balance) *model.Checks) error {
+			return checks(transfer.Debit()).Funds(&model.Funds{Available: testBalance.Operation})
+		}).Once()
+	err := srv.Transfer(context.Background(), transfer)
+	require.NoError(t, err)
+	transfer.Amount = decimal.NewFromInt(300)
+	rep.On("Transfer", mock.Anything, transfer, mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(func(_ context.Context, transfer *model.Transfer, _ *model.JournalEntry, checks func(*model.Balance) *model.Checks) error {
+			return checks(transfer.Debit()).Funds(&model.Funds{Available: testBalance.Operation})
+		}).Once()
+	err = srv.Transfer(context.Background(), transfer)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	rep.AssertExpectations(t)
+}
+
+func TestWrongTransfer(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	err := srv.Transfer(context.Background(), &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   testBalance.ProfileID,
+		Amount:        decimal.NewFromInt(100),
+		Currency:      model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.SelfTransfer))
+	err = srv.Transfer(context.Background(), &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(-100),
+		Currency:      model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidAmount))
+	rep.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHold(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	hold := &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Amount:    decimal.NewFromInt(100),
+		Currency:  model.DefaultCurrency,
+	}
+	rep.On("Hold", mock.Anything, hold, time.Hour, mock.Anything).
+		Return(func(_ context.Context, _ *model.Hold, _ time.Duration, checks *model.Checks) error {
+			return checks.Funds(&model.Funds{Available: testBalance.Operation})
+		}).Once()
+	err := srv.Hold(context.Background(), hold)
+	require.NoError(t, err)
+	hold.Amount = decimal.NewFromInt(300)
+	rep.On("Hold", mock.Anything, hold, time.Hour, mock.Anything).
+		Return(func(_ context.Context, _ *model.Hold, _ time.Duration, checks *model.Checks) error {
+			return checks.Funds(&model.Funds{Available: testBalance.Operation})
+		}).Once()
+	err = srv.Hold(context.Background(), hold)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	hold.Amount = decimal.Zero
+	err = srv.Hold(context.Background(), hold)
+	require.ErrorIs(t, err, berrors.New(berrors.ZeroAmount))
+	rep.AssertExpectations(t)
+}
+
+func TestCaptureHold(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	holdID := uuid.New()
+	operation := &model.Balance{BalanceID: uuid.New(), ProfileID: testBalance.ProfileID, Operation: decimal.NewFromInt(-50)}
+	rep.On("CaptureHold", mock.Anything, holdID, decimal.NewFromInt(50), mock.Anything).Return(operation, nil).Once()
+	captured, err := srv.CaptureHold(context.Background(), holdID, decimal.NewFromInt(50))
+	require.NoError(t, err)
+	require.Equal(t, operation, captured)
+	_, err = srv.CaptureHold(context.Background(), holdID, decimal.NewFromInt(-50))
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidAmount))
+	rep.AssertExpectations(t)
+}
+
+func TestWatchBalance(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rep.On("GetBalance", mock.Anything, testBalance.ProfileID, model.DefaultCurrency).
+		Return(&model.Funds{Total: decimal.NewFromInt(100), Available: decimal.NewFromInt(100)}, nil).Once()
+	rep.On("GetBalance", mock.Anything, testBalance.ProfileID, model.DefaultCurrency).
+		Return(&model.Funds{Total: decimal.NewFromInt(150), Available: decimal.NewFromInt(120)}, nil)
+	sent := make(chan *model.Funds)
+	done := make(chan error)
+	go func() {
+		done <- srv.WatchBalance(ctx, testBalance.ProfileID, model.DefaultCurrency, func(funds *model.Funds) error {
+			sent <- funds
+			return nil
+		})
+	}()
+	require.Equal(t, "100", (<-sent).Total.String())
+	srv.watchers.Publish(&model.BalanceChange{ProfileID: testBalance.ProfileID, Currency: model.DefaultCurrency})
+	funds := <-sent
+	require.Equal(t, "150", funds.Total.String())
+	require.Equal(t, "120", funds.Available.String())
+	srv.watchers.Publish(&model.BalanceChange{ProfileID: testBalance.ProfileID, Currency: model.DefaultCurrency})
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestBalanced(t *testing.T) {
+	transfer := &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(100),
+		Currency:      model.DefaultCurrency,
+	}
+	testCases := []struct {
+		name  string
+		entry *model.JournalEntry
+		code  string
+	}{
+		{name: "operation", entry: testBalance.Entry()},
+		{name: "transfer", entry: transfer.Entry()},
+		{name: "single posting", entry: &model.JournalEntry{EntryID: uuid.New(), Postings: testBalance.Entry().Postings[:1]},
+			code: berrors.UnbalancedEntry},
+		{name: "unbalanced", entry: &model.JournalEntry{EntryID: uuid.New(), Postings: []*model.Posting{
+			{Account: model.Wallet(testBalance.ProfileID, model.DefaultCurrency), Amount: decimal.NewFromInt(100)},
+			{Account: model.SystemAccount(model.FeeAccount, model.DefaultCurrency), Amount: decimal.NewFromInt(-1)},
+		}}, code: berrors.UnbalancedEntry},
+		{name: "currency mismatch", entry: &model.JournalEntry{EntryID: uuid.New(), Postings: []*model.Posting{
+			{Account: model.Wallet(testBalance.ProfileID, model.DefaultCurrency), Amount: decimal.NewFromInt(100)},
+			{Account: model.SystemAccount(model.SettlementAccount, "EUR"), Amount: decimal.NewFromInt(-100)},
+		}}, code: berrors.CurrencyMismatch},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := balanced(tc.entry)
+			if tc.code == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, berrors.New(tc.code))
+		})
+	}
+}
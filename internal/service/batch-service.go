@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/metrics"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// BatchBalanceOperations is a method of BalanceService that checks operations like BalanceOperation and records them
+// in one transaction. In atomic mode nothing is recorded if any operation fails and its error is returned with its index,
+// otherwise the result of every operation is returned.
+func (b *BalanceService) BatchBalanceOperations(ctx context.Context, balances []*model.Balance, atomic bool) ([]*model.BatchOperation, error) {
+	ctx, span := tracing.Start(ctx, "service.BatchBalanceOperations", tracing.BatchSizeKey.Int(len(balances)))
+	defer span.End()
+	batch := make([]*model.BatchOperation, 0, len(balances))
+	prepared := make([]*model.BatchOperation, 0, len(balances))
+	seen := make(map[uuid.UUID]bool, len(balances))
+	for i, balance := range balances {
+		operation := &model.BatchOperation{Balance: balance}
+		batch = append(batch, operation)
+		operation.Entry, operation.Checks, operation.Err = prepareOperation(balance)
+		if operation.Err == nil && seen[balance.BalanceID] {
+			operation.Err = berrors.New(berrors.DuplicateOperation)
+		}
+		seen[balance.BalanceID] = true
+		if operation.Err != nil {
+			if atomic {
+				err := atIndex(operation.Err, i)
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+			continue
+		}
+		prepared = append(prepared, operation)
+	}
+	if len(prepared) == 0 {
+		return batch, nil
+	}
+	err := b.bRep.BatchBalanceOperations(ctx, prepared, atomic)
+	if err != nil {
+		tracing.RecordError(span, err)
+		metrics.ObserveRejection(err)
+		// in atomic mode the repository returns the error of failed operation itself
+		for i, operation := range batch {
+			if atomic && operation.Err == err {
+				return nil, atIndex(fmt.Errorf("batchBalanceOperations %w", err), i)
+			}
+		}
+		return nil, fmt.Errorf("batchBalanceOperations %w", err)
+	}
+	for _, operation := range prepared {
+		if operation.Err != nil {
+			metrics.ObserveRejection(operation.Err)
+			continue
+		}
+		metrics.ObserveOperation(operation.Balance)
+	}
+	return batch, nil
+}
+
+// atIndex adds the index of failed operation of batch to its business error
+func atIndex(err error, index int) error {
+	var businessErr *berrors.BusinessError
+	if errors.As(err, &businessErr) {
+		businessErr.WithMetadata(berrors.IndexKey, strconv.Itoa(index))
+	}
+	return err
+}
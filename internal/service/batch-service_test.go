@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func batchOf(amounts ...int64) []*model.Balance {
+	balances := make([]*model.Balance, 0, len(amounts))
+	for _, amount := range amounts {
+		balances = append(balances, &model.Balance{
+			BalanceID: uuid.New(),
+			ProfileID: testBalance.ProfileID,
+			Operation: decimal.NewFromInt(amount),
+			Currency:  model.DefaultCurrency,
+		})
+	}
+	return balances
+}
+
+func TestBatchBalanceOperations(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	balances := batchOf(100, 0, -150)
+	rep.On("BatchBalanceOperations", mock.Anything, mock.MatchedBy(func(batch []*model.BatchOperation) bool {
+		return len(batch) == 2 && batch[0].Checks.Funds == nil && batch[1].Checks.Funds != nil
+	}), false).Return(func(_ context.Context, batch []*model.BatchOperation, _ bool) error {
+		funds := &model.Funds{Available: decimal.NewFromInt(100)}
+		batch[1].Err = batch[1].Checks.Funds(funds)
+		return nil
+	}).Once()
+	batch, err := srv.BatchBalanceOperations(context.Background(), balances, false)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	require.NoError(t, batch[0].Err)
+	require.Equal(t, model.Deposit, batch[0].Balance.OperationType)
+	require.ErrorIs(t, batch[1].Err, berrors.New(berrors.ZeroAmount))
+	require.ErrorIs(t, batch[2].Err, berrors.New(berrors.NotEnoughMoney))
+	rep.AssertExpectations(t)
+}
+
+func TestAtomicBatchBalanceOperations(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("BatchBalanceOperations", mock.Anything, mock.AnythingOfType("[]*model.BatchOperation"), true).
+		Return(func(_ context.Context, batch []*model.BatchOperation, _ bool) error {
+			batch[1].Err = batch[1].Checks.Funds(&model.Funds{Available: decimal.NewFromInt(100)})
+			return batch[1].Err
+		}).Once()
+	_, err := srv.BatchBalanceOperations(context.Background(), batchOf(100, -150), true)
+	require.ErrorIs(t, err, berrors.New(berrors.NotEnoughMoney))
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, "1", e.Metadata[berrors.IndexKey])
+
+	_, err = srv.BatchBalanceOperations(context.Background(), batchOf(100, 50, 0), true)
+	require.ErrorIs(t, err, berrors.New(berrors.ZeroAmount))
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, "2", e.Metadata[berrors.IndexKey])
+	rep.AssertExpectations(t)
+}
+
+func TestBatchBalanceOperationsFailure(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("BatchBalanceOperations", mock.Anything, mock.AnythingOfType("[]*model.BatchOperation"), false).
+		Return(berrors.New(berrors.IdempotencyConflict)).Once()
+	_, err := srv.BatchBalanceOperations(context.Background(), batchOf(0, 100), false)
+	var e *berrors.BusinessError
+	require.ErrorAs(t, err, &e)
+	require.NotContains(t, e.Metadata, berrors.IndexKey)
+
+	rep.On("BatchBalanceOperations", mock.Anything, mock.AnythingOfType("[]*model.BatchOperation"), true).
+		Return(berrors.New(berrors.IdempotencyConflict)).Once()
+	_, err = srv.BatchBalanceOperations(context.Background(), batchOf(100, 50), true)
+	require.ErrorAs(t, err, &e)
+	require.NotContains(t, e.Metadata, berrors.IndexKey)
+	rep.AssertExpectations(t)
+}
+
+func TestBatchBalanceOperationsDuplicate(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	balances := batchOf(100, 50)
+	balances[1].BalanceID = balances[0].BalanceID
+	_, err := srv.BatchBalanceOperations(context.Background(), balances, true)
+	require.ErrorIs(t, err, berrors.New(berrors.DuplicateOperation))
+	rep.AssertExpectations(t)
+}
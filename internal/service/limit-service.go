@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// GetLimits is a method of BalanceService that returns limits of profile in currency, default limits
+// are returned if profile has no own limits
+func (b *BalanceService) GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error) {
+	limits, err := b.bRep.GetLimits(ctx, profileID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("getLimits %w", err)
+	}
+	return limits, nil
+}
+
+// SetLimit is a method of BalanceService that calls method of Repository, zero amount forbids the operations
+func (b *BalanceService) SetLimit(ctx context.Context, limit *model.Limit) error {
+	if limit.Amount.IsNegative() {
+		return berrors.New(berrors.InvalidAmount).WithMetadata(berrors.RequiredKey, limit.Amount.String())
+	}
+	err := b.bRep.SetLimit(ctx, limit)
+	if err != nil {
+		return fmt.Errorf("setLimit %w", err)
+	}
+	return nil
+}
+
+// SetCreditLimit is a method of BalanceService that lets balance of profile go negative down to minus credit limit
+func (b *BalanceService) SetCreditLimit(ctx context.Context, line *model.CreditLine) error {
+	if line.CreditLimit.IsNegative() {
+		return berrors.New(berrors.InvalidAmount).WithMetadata(berrors.RequiredKey, line.CreditLimit.String())
+	}
+	err := b.bRep.SetCreditLimit(ctx, line)
+	if err != nil {
+		return fmt.Errorf("setCreditLimit %w", err)
+	}
+	return nil
+}
+
+// withinLimits returns the check of limits for operation with the amount, the error tells about
+// the exceeded limit with the least remaining allowance
+func withinLimits(amount decimal.Decimal, currency string) func(usages []*model.LimitUsage) error {
+	return func(usages []*model.LimitUsage) error {
+		var exceeded *model.LimitUsage
+		for _, usage := range usages {
+			if amount.GreaterThan(usage.Remaining()) && (exceeded == nil || usage.Remaining().LessThan(exceeded.Remaining())) {
+				exceeded = usage
+			}
+		}
+		if exceeded == nil {
+			return nil
+		}
+		return berrors.New(berrors.LimitExceeded).
+			WithMetadata(berrors.RequiredKey, amount.String()).
+			WithMetadata(berrors.RemainingKey, exceeded.Remaining().String()).
+			WithMetadata(berrors.PeriodKey, string(exceeded.Limit.Period)).
+			WithMetadata(berrors.CurrencyKey, currency)
+	}
+}
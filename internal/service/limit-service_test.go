@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithinLimits(t *testing.T) {
+	daily := &model.LimitUsage{
+		Limit: &model.Limit{Period: model.Daily, Amount: decimal.NewFromInt(100)},
+		Used:  decimal.NewFromInt(70),
+	}
+	monthly := &model.LimitUsage{
+		Limit: &model.Limit{Period: model.Monthly, Amount: decimal.NewFromInt(1000)},
+		Used:  decimal.NewFromInt(990),
+	}
+	perOperation := &model.LimitUsage{
+		Limit: &model.Limit{Period: model.PerOperation, Amount: decimal.NewFromInt(50)},
+		Used:  decimal.Zero,
+	}
+	testCases := []struct {
+		name      string
+		amount    int64
+		usages    []*model.LimitUsage
+		period    model.LimitPeriod
+		remaining string
+	}{
+		{name: "no limits", amount: 1000},
+		{name: "within limits", amount: 10, usages: []*model.LimitUsage{daily, monthly, perOperation}},
+		{name: "exact remaining", amount: 30, usages: []*model.LimitUsage{daily}},
+		{name: "daily exceeded", amount: 31, usages: []*model.LimitUsage{daily}, period: model.Daily, remaining: "30"},
+		{name: "least remaining", amount: 40, usages: []*model.LimitUsage{daily, monthly}, period: model.Monthly, remaining: "10"},
+		{name: "per operation", amount: 51, usages: []*model.LimitUsage{perOperation}, period: model.PerOperation, remaining: "50"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := withinLimits(decimal.NewFromInt(tc.amount), model.DefaultCurrency)(tc.usages)
+			if tc.period == "" {
+				require.NoError(t, err)
+				return
+			}
+			var e *berrors.BusinessError
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, berrors.LimitExceeded, e.Code)
+			require.Equal(t, string(tc.period), e.Metadata[berrors.PeriodKey])
+			require.Equal(t, tc.remaining, e.Metadata[berrors.RemainingKey])
+		})
+	}
+}
+
+func TestDepositLimitExceeded(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Limits([]*model.LimitUsage{{
+				Limit: &model.Limit{Direction: model.DepositLimit, Period: model.Daily, Amount: decimal.NewFromInt(100)},
+				Used:  decimal.NewFromInt(100),
+			}})
+		}).Once()
+	err := srv.BalanceOperation(context.Background(), &model.Balance{
+		BalanceID: uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Operation: decimal.NewFromInt(1),
+		Currency:  model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.LimitExceeded))
+	rep.AssertExpectations(t)
+}
+
+func TestWithdrawalLimitExceeded(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	usages := []*model.LimitUsage{{
+		Limit: &model.Limit{Direction: model.WithdrawalLimit, Period: model.Daily, Amount: decimal.NewFromInt(100)},
+		Used:  decimal.NewFromInt(95),
+	}}
+
+	rep.On("Transfer", mock.Anything, mock.AnythingOfType("*model.Transfer"), mock.AnythingOfType("*model.JournalEntry"), mock.Anything).
+		Return(func(_ context.Context, transfer *model.Transfer, _ *model.JournalEntry, checks func(*model.Balance) *model.Checks) error {
+			return checks(transfer.Debit()).Limits(usages)
+		}).Once()
+	err := srv.Transfer(context.Background(), &model.Transfer{
+		TransferID:    uuid.New(),
+		FromProfileID: testBalance.ProfileID,
+		ToProfileID:   uuid.New(),
+		Amount:        decimal.NewFromInt(10),
+		Currency:      model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.LimitExceeded))
+
+	rep.On("Hold", mock.Anything, mock.AnythingOfType("*model.Hold"), time.Hour, mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Hold, _ time.Duration, checks *model.Checks) error {
+			return checks.Limits(usages)
+		}).Once()
+	err = srv.Hold(context.Background(), &model.Hold{
+		HoldID:    uuid.New(),
+		ProfileID: testBalance.ProfileID,
+		Amount:    decimal.NewFromInt(10),
+		Currency:  model.DefaultCurrency,
+	})
+	require.ErrorIs(t, err, berrors.New(berrors.LimitExceeded))
+
+	hold := &model.Hold{HoldID: uuid.New(), ProfileID: testBalance.ProfileID, Captured: decimal.NewFromInt(10), Currency: model.DefaultCurrency}
+	rep.On("CaptureHold", mock.Anything, hold.HoldID, decimal.Zero, mock.Anything).
+		Return(nil, func(_ context.Context, _ uuid.UUID, _ decimal.Decimal, checks func(*model.Balance) *model.Checks) error {
+			capture := checks(hold.Capture())
+			require.Nil(t, capture.Funds)
+			return capture.Limits(usages)
+		}).Once()
+	_, err = srv.CaptureHold(context.Background(), hold.HoldID, decimal.Zero)
+	require.ErrorIs(t, err, berrors.New(berrors.LimitExceeded))
+	rep.AssertExpectations(t)
+}
+
+func TestSetLimit(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	limit := &model.Limit{
+		ProfileID: testBalance.ProfileID,
+		Currency:  model.DefaultCurrency,
+		Direction: model.WithdrawalLimit,
+		Period:    model.Daily,
+		Amount:    decimal.NewFromInt(500),
+	}
+	rep.On("SetLimit", mock.Anything, limit).Return(nil).Once()
+	err := srv.SetLimit(context.Background(), limit)
+	require.NoError(t, err)
+	err = srv.SetLimit(context.Background(), &model.Limit{Amount: decimal.NewFromInt(-1)})
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidAmount))
+	rep.AssertExpectations(t)
+}
+
+func TestEnoughMoney(t *testing.T) {
+	testCases := []struct {
+		name        string
+		available   int64
+		creditLimit int64
+		amount      int64
+		enough      bool
+	}{
+		{name: "less than balance", available: 100, amount: 99, enough: true},
+		{name: "exact balance", available: 100, amount: 100, enough: true},
+		{name: "more than balance", available: 100, amount: 101},
+		{name: "covered by credit", available: 100, creditLimit: 50, amount: 120, enough: true},
+		{name: "exact credit", available: 100, creditLimit: 50, amount: 150, enough: true},
+		{name: "more than credit", available: 100, creditLimit: 50, amount: 151},
+		{name: "negative balance within credit", available: -30, creditLimit: 50, amount: 20, enough: true},
+		{name: "negative balance beyond credit", available: -30, creditLimit: 50, amount: 21},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := enoughMoney(decimal.NewFromInt(tc.amount), model.DefaultCurrency)(&model.Funds{
+				Available:   decimal.NewFromInt(tc.available),
+				CreditLimit: decimal.NewFromInt(tc.creditLimit),
+			})
+			if tc.enough {
+				require.NoError(t, err)
+				return
+			}
+			var e *berrors.BusinessError
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, berrors.NotEnoughMoney, e.Code)
+			require.Equal(t, decimal.NewFromInt(tc.creditLimit).String(), e.Metadata[berrors.CreditLimitKey])
+		})
+	}
+}
+
+func TestSetCreditLimit(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	line := &model.CreditLine{ProfileID: testBalance.ProfileID, Currency: model.DefaultCurrency, CreditLimit: decimal.NewFromInt(300)}
+	rep.On("SetCreditLimit", mock.Anything, line).Return(nil).Once()
+	err := srv.SetCreditLimit(context.Background(), line)
+	require.NoError(t, err)
+	err = srv.SetCreditLimit(context.Background(), &model.CreditLine{CreditLimit: decimal.NewFromInt(-1)})
+	require.ErrorIs(t, err, berrors.New(berrors.InvalidAmount))
+	rep.AssertExpectations(t)
+}
@@ -6,9 +6,14 @@ import (
 	context "context"
 
 	model "github.com/artnikel/BalanceService/internal/model"
-	mock "github.com/stretchr/testify/mock"
 
 	uuid "github.com/google/uuid"
+
+	decimal "github.com/shopspring/decimal"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // BalanceRepository is an autogenerated mock type for the BalanceRepository type
@@ -16,13 +21,283 @@ type BalanceRepository struct {
 	mock.Mock
 }
 
-// BalanceOperation provides a mock function with given fields: ctx, balance
-func (_m *BalanceRepository) BalanceOperation(ctx context.Context, balance *model.Balance) error {
-	ret := _m.Called(ctx, balance)
+// BatchBalanceOperations provides a mock function with given fields: ctx, batch, atomic
+func (_m *BalanceRepository) BatchBalanceOperations(ctx context.Context, batch []*model.BatchOperation, atomic bool) error {
+	ret := _m.Called(ctx, batch, atomic)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.BatchOperation, bool) error); ok {
+		r0 = rf(ctx, batch, atomic)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CancelSchedule provides a mock function with given fields: ctx, scheduleID
+func (_m *BalanceRepository) CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error) {
+	ret := _m.Called(ctx, scheduleID)
+
+	var r0 *model.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *model.Schedule); ok {
+		r0 = rf(ctx, scheduleID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, scheduleID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CaptureHold provides a mock function with given fields: ctx, holdID, amount, checks
+func (_m *BalanceRepository) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal, checks func(*model.Balance) *model.Checks) (*model.Balance, error) {
+	ret := _m.Called(ctx, holdID, amount, checks)
+
+	var r0 *model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, decimal.Decimal, func(*model.Balance) *model.Checks) *model.Balance); ok {
+		r0 = rf(ctx, holdID, amount, checks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Balance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, decimal.Decimal, func(*model.Balance) *model.Checks) error); ok {
+		r1 = rf(ctx, holdID, amount, checks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeAccount provides a mock function with given fields: ctx, change, apply
+func (_m *BalanceRepository) ChangeAccount(ctx context.Context, change *model.AccountChange, apply func(*model.AccountState) error) (*model.AccountState, error) {
+	ret := _m.Called(ctx, change, apply)
+
+	var r0 *model.AccountState
+	if rf, ok := ret.Get(0).(func(context.Context, *model.AccountChange, func(*model.AccountState) error) *model.AccountState); ok {
+		r0 = rf(ctx, change, apply)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.AccountState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.AccountChange, func(*model.AccountState) error) error); ok {
+		r1 = rf(ctx, change, apply)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CheckedBalanceOperation provides a mock function with given fields: ctx, balance, entry, checks
+func (_m *BalanceRepository) CheckedBalanceOperation(ctx context.Context, balance *model.Balance, entry *model.JournalEntry, checks *model.Checks) error {
+	ret := _m.Called(ctx, balance, entry, checks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Balance, *model.JournalEntry, *model.Checks) error); ok {
+		r0 = rf(ctx, balance, entry, checks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateSchedule provides a mock function with given fields: ctx, schedule
+func (_m *BalanceRepository) CreateSchedule(ctx context.Context, schedule *model.Schedule) error {
+	ret := _m.Called(ctx, schedule)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Schedule) error); ok {
+		r0 = rf(ctx, schedule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DueSchedules provides a mock function with given fields: ctx, now, limit
+func (_m *BalanceRepository) DueSchedules(ctx context.Context, now time.Time, limit int) ([]*model.Schedule, error) {
+	ret := _m.Called(ctx, now, limit)
+
+	var r0 []*model.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []*model.Schedule); ok {
+		r0 = rf(ctx, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Schedule)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExpireHolds provides a mock function with given fields: ctx
+func (_m *BalanceRepository) ExpireHolds(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalance provides a mock function with given fields: ctx, profileID, currency
+func (_m *BalanceRepository) GetBalance(ctx context.Context, profileID uuid.UUID, currency string) (*model.Funds, error) {
+	ret := _m.Called(ctx, profileID, currency)
+
+	var r0 *model.Funds
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) *model.Funds); ok {
+		r0 = rf(ctx, profileID, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Funds)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, profileID, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalanceAt provides a mock function with given fields: ctx, profileID, currency, asOf
+func (_m *BalanceRepository) GetBalanceAt(ctx context.Context, profileID uuid.UUID, currency string, asOf time.Time) (*model.Funds, error) {
+	ret := _m.Called(ctx, profileID, currency, asOf)
+
+	var r0 *model.Funds
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, time.Time) *model.Funds); ok {
+		r0 = rf(ctx, profileID, currency, asOf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Funds)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string, time.Time) error); ok {
+		r1 = rf(ctx, profileID, currency, asOf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHold provides a mock function with given fields: ctx, holdID
+func (_m *BalanceRepository) GetHold(ctx context.Context, holdID uuid.UUID) (*model.Hold, error) {
+	ret := _m.Called(ctx, holdID)
+
+	var r0 *model.Hold
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *model.Hold); ok {
+		r0 = rf(ctx, holdID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Hold)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, holdID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLimits provides a mock function with given fields: ctx, profileID, currency
+func (_m *BalanceRepository) GetLimits(ctx context.Context, profileID uuid.UUID, currency string) ([]*model.Limit, error) {
+	ret := _m.Called(ctx, profileID, currency)
+
+	var r0 []*model.Limit
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string) []*model.Limit); ok {
+		r0 = rf(ctx, profileID, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Limit)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string) error); ok {
+		r1 = rf(ctx, profileID, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOperation provides a mock function with given fields: ctx, balanceID
+func (_m *BalanceRepository) GetOperation(ctx context.Context, balanceID uuid.UUID) (*model.Balance, error) {
+	ret := _m.Called(ctx, balanceID)
+
+	var r0 *model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) *model.Balance); ok {
+		r0 = rf(ctx, balanceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Balance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = rf(ctx, balanceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Hold provides a mock function with given fields: ctx, hold, ttl, checks
+func (_m *BalanceRepository) Hold(ctx context.Context, hold *model.Hold, ttl time.Duration, checks *model.Checks) error {
+	ret := _m.Called(ctx, hold, ttl, checks)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.Balance) error); ok {
-		r0 = rf(ctx, balance)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Hold, time.Duration, *model.Checks) error); ok {
+		r0 = rf(ctx, hold, ttl, checks)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -30,20 +305,82 @@ func (_m *BalanceRepository) BalanceOperation(ctx context.Context, balance *mode
 	return r0
 }
 
-// GetBalance provides a mock function with given fields: ctx, bId
-func (_m *BalanceRepository) GetBalance(ctx context.Context, bId uuid.UUID) (float64, error) {
-	ret := _m.Called(ctx, bId)
+// ListOperations provides a mock function with given fields: ctx, filter
+func (_m *BalanceRepository) ListOperations(ctx context.Context, filter *model.OperationFilter) ([]*model.Balance, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []*model.Balance
+	if rf, ok := ret.Get(0).(func(context.Context, *model.OperationFilter) []*model.Balance); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Balance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.OperationFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSchedules provides a mock function with given fields: ctx, profileID
+func (_m *BalanceRepository) ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error) {
+	ret := _m.Called(ctx, profileID)
 
-	var r0 float64
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) float64); ok {
-		r0 = rf(ctx, bId)
+	var r0 []*model.Schedule
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) []*model.Schedule); ok {
+		r0 = rf(ctx, profileID)
 	} else {
-		r0 = ret.Get(0).(float64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Schedule)
+		}
 	}
 
 	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
-		r1 = rf(ctx, bId)
+		r1 = rf(ctx, profileID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListenBalanceChanges provides a mock function with given fields: ctx, listening, handle
+func (_m *BalanceRepository) ListenBalanceChanges(ctx context.Context, listening func(), handle func(*model.BalanceChange)) error {
+	ret := _m.Called(ctx, listening, handle)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(), func(*model.BalanceChange)) error); ok {
+		r0 = rf(ctx, listening, handle)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reconcile provides a mock function with given fields: ctx
+func (_m *BalanceRepository) Reconcile(ctx context.Context) ([]*model.Drift, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*model.Drift
+	if rf, ok := ret.Get(0).(func(context.Context) []*model.Drift); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Drift)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -51,6 +388,111 @@ func (_m *BalanceRepository) GetBalance(ctx context.Context, bId uuid.UUID) (flo
 	return r0, r1
 }
 
+// RecordAttempt provides a mock function with given fields: ctx, scheduleID, runTime
+func (_m *BalanceRepository) RecordAttempt(ctx context.Context, scheduleID uuid.UUID, runTime time.Time) (int, error) {
+	ret := _m.Called(ctx, scheduleID, runTime)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, time.Time) int); ok {
+		r0 = rf(ctx, scheduleID, runTime)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, time.Time) error); ok {
+		r1 = rf(ctx, scheduleID, runTime)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordRun provides a mock function with given fields: ctx, run, nextRunTime
+func (_m *BalanceRepository) RecordRun(ctx context.Context, run *model.ScheduleRun, nextRunTime time.Time) error {
+	ret := _m.Called(ctx, run, nextRunTime)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.ScheduleRun, time.Time) error); ok {
+		r0 = rf(ctx, run, nextRunTime)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseHold provides a mock function with given fields: ctx, holdID
+func (_m *BalanceRepository) ReleaseHold(ctx context.Context, holdID uuid.UUID) error {
+	ret := _m.Called(ctx, holdID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
+		r0 = rf(ctx, holdID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReverseOperation provides a mock function with given fields: ctx, reversal, entry, checks
+func (_m *BalanceRepository) ReverseOperation(ctx context.Context, reversal *model.Balance, entry *model.JournalEntry, checks *model.Checks) error {
+	ret := _m.Called(ctx, reversal, entry, checks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Balance, *model.JournalEntry, *model.Checks) error); ok {
+		r0 = rf(ctx, reversal, entry, checks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetCreditLimit provides a mock function with given fields: ctx, line
+func (_m *BalanceRepository) SetCreditLimit(ctx context.Context, line *model.CreditLine) error {
+	ret := _m.Called(ctx, line)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.CreditLine) error); ok {
+		r0 = rf(ctx, line)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLimit provides a mock function with given fields: ctx, limit
+func (_m *BalanceRepository) SetLimit(ctx context.Context, limit *model.Limit) error {
+	ret := _m.Called(ctx, limit)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Limit) error); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Transfer provides a mock function with given fields: ctx, transfer, entry, checks
+func (_m *BalanceRepository) Transfer(ctx context.Context, transfer *model.Transfer, entry *model.JournalEntry, checks func(*model.Balance) *model.Checks) error {
+	ret := _m.Called(ctx, transfer, entry, checks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Transfer, *model.JournalEntry, func(*model.Balance) *model.Checks) error); ok {
+		r0 = rf(ctx, transfer, entry, checks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewBalanceRepository interface {
 	mock.TestingT
 	Cleanup(func())
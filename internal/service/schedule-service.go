@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/logging"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minScheduleInterval is the shortest allowed time between runs of schedule
+	minScheduleInterval = time.Minute
+	// maxRunAttempts is the number of attempts of run whose operation fails for reasons other than business rules,
+	// the run is recorded as failed after the last one
+	maxRunAttempts = 5
+)
+
+// ScheduleOperation is a method of BalanceService that checks the operation and the expression of schedule
+// and records schedule with the time of its first run, times of cron expressions are in UTC
+func (b *BalanceService) ScheduleOperation(ctx context.Context, schedule *model.Schedule) error {
+	if schedule.OperationType == "" {
+		schedule.OperationType = model.DefaultOperationType(schedule.Operation)
+	}
+	_, _, err := prepareOperation(schedule.Balance())
+	if err != nil {
+		return fmt.Errorf("prepareOperation %w", err)
+	}
+	spec, err := parseExpression(schedule.Expression)
+	if err != nil {
+		return fmt.Errorf("parseExpression %w", err)
+	}
+	schedule.Status = model.ScheduleActive
+	schedule.NextRunTime = spec.Next(time.Now().UTC())
+	err = b.bRep.CreateSchedule(ctx, schedule)
+	if err != nil {
+		return fmt.Errorf("createSchedule %w", err)
+	}
+	return nil
+}
+
+// CancelSchedule is a method of BalanceService that calls method of Repository, cancelled schedule makes no more runs
+func (b *BalanceService) CancelSchedule(ctx context.Context, scheduleID uuid.UUID) (*model.Schedule, error) {
+	schedule, err := b.bRep.CancelSchedule(ctx, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("cancelSchedule %w", err)
+	}
+	return schedule, nil
+}
+
+// ListSchedules is a method of BalanceService that calls method of Repository
+func (b *BalanceService) ListSchedules(ctx context.Context, profileID uuid.UUID) ([]*model.Schedule, error) {
+	schedules, err := b.bRep.ListSchedules(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("listSchedules %w", err)
+	}
+	return schedules, nil
+}
+
+// RunDueSchedules is a method of BalanceService that makes operations of due schedules by BalanceOperation and records
+// results of runs, it returns the number of due schedules. Rejected operations such as ones without enough money are
+// recorded as failed runs and aren`t retried. Runs missed while no instance was running schedules aren`t made one by one,
+// schedule makes one run and goes on from now. Failure of one schedule is logged and doesn`t stop the others,
+// the error is returned if any of them failed.
+func (b *BalanceService) RunDueSchedules(ctx context.Context, limit int) (int, error) {
+	now := time.Now().UTC()
+	schedules, err := b.bRep.DueSchedules(ctx, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("dueSchedules %w", err)
+	}
+	failed := 0
+	for _, schedule := range schedules {
+		err = b.runSchedule(ctx, schedule, now)
+		if err != nil {
+			failed++
+			logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+				logging.ScheduleIDKey: schedule.ScheduleID,
+				logging.ProfileIDKey:  schedule.ProfileID,
+			}).Error("run of schedule failed")
+		}
+	}
+	if failed > 0 {
+		return len(schedules), fmt.Errorf("runs of %d of %d due schedules failed", failed, len(schedules))
+	}
+	return len(schedules), nil
+}
+
+// runSchedule makes the operation of due run of schedule, failure which isn`t caused by business rules leaves
+// the run due, so it is made again with the same id and its operation isn`t recorded twice.
+// The run is recorded as failed after maxRunAttempts such failures.
+func (b *BalanceService) runSchedule(ctx context.Context, schedule *model.Schedule, now time.Time) error {
+	spec, err := parseExpression(schedule.Expression)
+	if err != nil {
+		return fmt.Errorf("parseExpression %w", err)
+	}
+	run := &model.ScheduleRun{
+		RunID:      schedule.RunID(),
+		ScheduleID: schedule.ScheduleID,
+		RunTime:    schedule.NextRunTime,
+		Status:     model.RunSucceeded,
+	}
+	err = b.BalanceOperation(ctx, schedule.Balance())
+	var businessErr *berrors.BusinessError
+	switch {
+	case errors.As(err, &businessErr):
+		run.Status, run.ErrorCode, run.Reason = model.RunFailed, businessErr.Code, err.Error()
+	case err != nil:
+		attempts, errAttempt := b.bRep.RecordAttempt(ctx, schedule.ScheduleID, schedule.NextRunTime)
+		if errAttempt != nil {
+			return errors.Join(fmt.Errorf("balanceOperation %w", err), fmt.Errorf("recordAttempt %w", errAttempt))
+		}
+		if attempts < maxRunAttempts {
+			return fmt.Errorf("balanceOperation %w", err)
+		}
+		run.Status, run.ErrorCode = model.RunFailed, berrors.RunAttemptsExhausted
+		run.Reason = fmt.Sprintf("operation failed %d times", attempts)
+	}
+	err = b.bRep.RecordRun(ctx, run, spec.Next(now))
+	if err != nil {
+		return fmt.Errorf("recordRun %w", err)
+	}
+	logger := logging.FromContext(ctx).WithFields(logrus.Fields{
+		logging.ScheduleIDKey:  schedule.ScheduleID,
+		logging.ProfileIDKey:   schedule.ProfileID,
+		logging.OperationIDKey: run.RunID,
+	})
+	if run.Status == model.RunFailed {
+		logger.WithField(logging.CodeKey, run.ErrorCode).Warn("scheduled operation is rejected")
+		return nil
+	}
+	logger.Info("scheduled operation is made")
+	return nil
+}
+
+// parseExpression parses cron expression with five fields or descriptor like "@daily" or "@every 1h",
+// schedules which run more often than minScheduleInterval aren`t allowed
+func parseExpression(expression string) (cron.Schedule, error) {
+	spec, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, berrors.New(berrors.InvalidSchedule).WithMetadata(berrors.ExpressionKey, expression)
+	}
+	next := spec.Next(time.Now().UTC())
+	if spec.Next(next).Sub(next) < minScheduleInterval {
+		return nil, berrors.New(berrors.InvalidSchedule).WithMetadata(berrors.ExpressionKey, expression)
+	}
+	return spec, nil
+}
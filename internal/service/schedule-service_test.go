@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	berrors "github.com/artnikel/BalanceService/internal/errors"
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/artnikel/BalanceService/internal/service/mocks"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchedule(amount int64, expression string) *model.Schedule {
+	return &model.Schedule{
+		ScheduleID: uuid.New(),
+		ProfileID:  testBalance.ProfileID,
+		Operation:  decimal.NewFromInt(amount),
+		Currency:   model.DefaultCurrency,
+		Expression: expression,
+	}
+}
+
+func TestScheduleOperation(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	rep.On("CreateSchedule", mock.Anything, mock.AnythingOfType("*model.Schedule")).Return(nil).Once()
+	schedule := testSchedule(-100, "0 9 1 * *")
+	err := srv.ScheduleOperation(context.Background(), schedule)
+	require.NoError(t, err)
+	require.Equal(t, model.ScheduleActive, schedule.Status)
+	require.Equal(t, model.Withdrawal, schedule.OperationType)
+	require.Equal(t, 1, schedule.NextRunTime.Day())
+	require.Equal(t, 9, schedule.NextRunTime.Hour())
+	require.True(t, schedule.NextRunTime.After(time.Now()))
+
+	for _, expression := range []string{"every day", "@every 10s", "* * * * * *"} {
+		err = srv.ScheduleOperation(context.Background(), testSchedule(100, expression))
+		require.ErrorIs(t, err, berrors.New(berrors.InvalidSchedule))
+	}
+	err = srv.ScheduleOperation(context.Background(), testSchedule(0, "@daily"))
+	require.ErrorIs(t, err, berrors.New(berrors.ZeroAmount))
+	rep.AssertExpectations(t)
+}
+
+func TestRunDueSchedules(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	deposit, withdrawal := testSchedule(100, "@every 1h"), testSchedule(-500, "@every 1h")
+	deposit.OperationType, withdrawal.OperationType = model.Deposit, model.Withdrawal
+	deposit.NextRunTime = time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	withdrawal.NextRunTime = deposit.NextRunTime
+	rep.On("DueSchedules", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]*model.Schedule{deposit, withdrawal}, nil).Once()
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.BalanceID == deposit.RunID()
+	}), mock.AnythingOfType("*model.JournalEntry"), mock.AnythingOfType("*model.Checks")).Return(nil).Once()
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.BalanceID == withdrawal.RunID()
+	}), mock.AnythingOfType("*model.JournalEntry"), mock.AnythingOfType("*model.Checks")).
+		Return(func(_ context.Context, _ *model.Balance, _ *model.JournalEntry, checks *model.Checks) error {
+			return checks.Funds(&model.Funds{Available: decimal.NewFromInt(100)})
+		}).Once()
+	rep.On("RecordRun", mock.Anything, mock.MatchedBy(func(run *model.ScheduleRun) bool {
+		return run.ScheduleID == deposit.ScheduleID && run.Status == model.RunSucceeded && run.RunTime.Equal(deposit.NextRunTime)
+	}), mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now())
+	})).Return(nil).Once()
+	rep.On("RecordRun", mock.Anything, mock.MatchedBy(func(run *model.ScheduleRun) bool {
+		return run.ScheduleID == withdrawal.ScheduleID && run.Status == model.RunFailed && run.ErrorCode == berrors.NotEnoughMoney
+	}), mock.AnythingOfType("time.Time")).Return(nil).Once()
+	due, err := srv.RunDueSchedules(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, due)
+	rep.AssertExpectations(t)
+}
+
+func TestRunDueSchedulesFailure(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	failing, next := testSchedule(100, "@every 1h"), testSchedule(100, "@every 1h")
+	failing.OperationType, next.OperationType = model.Deposit, model.Deposit
+	rep.On("DueSchedules", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]*model.Schedule{failing, next}, nil).Once()
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.BalanceID == failing.RunID()
+	}), mock.AnythingOfType("*model.JournalEntry"), mock.AnythingOfType("*model.Checks")).Return(errors.New("connection refused")).Once()
+	rep.On("RecordAttempt", mock.Anything, failing.ScheduleID, failing.NextRunTime).Return(1, nil).Once()
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.MatchedBy(func(balance *model.Balance) bool {
+		return balance.BalanceID == next.RunID()
+	}), mock.AnythingOfType("*model.JournalEntry"), mock.AnythingOfType("*model.Checks")).Return(nil).Once()
+	rep.On("RecordRun", mock.Anything, mock.MatchedBy(func(run *model.ScheduleRun) bool {
+		return run.ScheduleID == next.ScheduleID && run.Status == model.RunSucceeded
+	}), mock.AnythingOfType("time.Time")).Return(nil).Once()
+	due, err := srv.RunDueSchedules(context.Background(), 10)
+	require.Error(t, err)
+	require.Equal(t, 2, due)
+	rep.AssertExpectations(t)
+}
+
+func TestRunDueSchedulesAttemptsExhausted(t *testing.T) {
+	rep := new(mocks.BalanceRepository)
+	srv := NewBalanceService(rep, time.Hour)
+	schedule := testSchedule(100, "@every 1h")
+	schedule.OperationType = model.Deposit
+	rep.On("DueSchedules", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]*model.Schedule{schedule}, nil).Once()
+	rep.On("CheckedBalanceOperation", mock.Anything, mock.AnythingOfType("*model.Balance"), mock.AnythingOfType("*model.JournalEntry"),
+		mock.AnythingOfType("*model.Checks")).Return(errors.New("connection refused")).Once()
+	rep.On("RecordAttempt", mock.Anything, schedule.ScheduleID, schedule.NextRunTime).Return(maxRunAttempts, nil).Once()
+	rep.On("RecordRun", mock.Anything, mock.MatchedBy(func(run *model.ScheduleRun) bool {
+		return run.ScheduleID == schedule.ScheduleID && run.Status == model.RunFailed && run.ErrorCode == berrors.RunAttemptsExhausted
+	}), mock.AnythingOfType("time.Time")).Return(nil).Once()
+	due, err := srv.RunDueSchedules(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, due)
+	rep.AssertExpectations(t)
+}
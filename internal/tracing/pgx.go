@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rowsAffectedKey is attribute of number of rows which were returned or changed by query
+const rowsAffectedKey = attribute.Key("db.rows_affected")
+
+// QueryTracer makes span of every query which is sent with traced context, queries of background workers
+// without span in context aren`t traced
+type QueryTracer struct{}
+
+// TraceQueryStart starts span of query as child of span in context
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	operation := queryOperation(data.SQL)
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, "pgx "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationKey.String(operation), semconv.DBStatement(data.SQL)))
+	return ctx
+}
+
+// TraceQueryEnd ends span of query which was started by TraceQueryStart
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		RecordError(span, data.Err)
+	} else {
+		span.SetAttributes(rowsAffectedKey.Int64(data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// queryOperation returns the first keyword of query like SELECT or INSERT
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
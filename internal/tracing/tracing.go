@@ -0,0 +1,81 @@
+// Package tracing makes OpenTelemetry spans of requests, business operations and queries to database
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracerName is the name of instrumentation which makes spans of service
+	tracerName = "github.com/artnikel/BalanceService"
+	// serviceName is the name of service in exported spans
+	serviceName = "balance-service"
+)
+
+// Attributes of spans which are set by all layers
+const (
+	// ProfileIDKey is attribute of id of profile
+	ProfileIDKey = attribute.Key("balance.profile_id")
+	// SignKey is attribute of sign of operation, 1 for deposits and -1 for withdrawals
+	SignKey = attribute.Key("balance.operation_sign")
+	// BatchSizeKey is attribute of number of operations in batch
+	BatchSizeKey = attribute.Key("balance.batch_size")
+	// HoldIDKey is attribute of id of hold
+	HoldIDKey = attribute.Key("balance.hold_id")
+	// OperationIDKey is attribute of id of operation
+	OperationIDKey = attribute.Key("balance.operation_id")
+)
+
+// Configure sets propagation of trace context and, if endpoint isn`t empty, exports spans to OTLP collector
+// by gRPC, without endpoint spans aren`t made. It returns the function which flushes spans and stops exporting.
+func Configure(ctx context.Context, endpoint string, insecure bool) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("new %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts span with name and attributes as child of span in context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError records err in span and marks span as failed
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Operation returns attributes of profile and sign of operation
+func Operation(balance *model.Balance) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		ProfileIDKey.String(balance.ProfileID.String()),
+		SignKey.Int(balance.Operation.Sign()),
+	}
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/artnikel/BalanceService/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/BalanceService/GetBalance"}
+
+// record makes spans of test be exported to memory
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return exporter
+}
+
+// attributes returns attributes of span by their keys
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	exporter := record(t)
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	_, err := UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		_, span := Start(ctx, "service.GetBalance")
+		span.End()
+		return nil, nil
+	})
+	require.NoError(t, err)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+	require.Equal(t, "BalanceService/GetBalance", server.Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	require.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+	attrs := attributes(server)
+	require.Equal(t, "BalanceService", attrs[semconv.RPCServiceKey].AsString())
+	require.Equal(t, "GetBalance", attrs[semconv.RPCMethodKey].AsString())
+	require.Equal(t, int64(codes.OK), attrs[semconv.RPCGRPCStatusCodeKey].AsInt64())
+}
+
+func TestUnaryServerInterceptorFailure(t *testing.T) {
+	exporter := record(t)
+	_, err := UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "OPERATION_NOT_FOUND")
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, otelcodes.Error, spans[0].Status.Code)
+	require.False(t, spans[0].Parent.IsValid())
+	require.Equal(t, int64(codes.NotFound), attributes(spans[0])[semconv.RPCGRPCStatusCodeKey].AsInt64())
+}
+
+// testStream is server stream with context
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	exporter := record(t)
+	stream := &testStream{ctx: metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))}
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/BalanceService/WatchBalance", IsServerStream: true}
+	err := StreamServerInterceptor()(nil, stream, streamInfo, func(srv any, ss grpc.ServerStream) error {
+		_, span := Start(ss.Context(), "service.WatchBalance")
+		span.End()
+		return status.Error(codes.Canceled, "context canceled")
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+	require.Equal(t, "BalanceService/WatchBalance", server.Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	require.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+	require.Equal(t, otelcodes.Error, server.Status.Code)
+	require.Equal(t, int64(codes.Canceled), attributes(server)[semconv.RPCGRPCStatusCodeKey].AsInt64())
+}
+
+func TestQueryTracer(t *testing.T) {
+	exporter := record(t)
+	tracer := QueryTracer{}
+	untraced := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(untraced, nil, pgx.TraceQueryEndData{})
+	require.Empty(t, exporter.GetSpans())
+
+	ctx, parent := Start(context.Background(), "repository.GetBalance")
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "\n\tselect money FROM ledger_accounts"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "INSERT INTO balance"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection refused")})
+	parent.End()
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	selected, inserted := spans[0], spans[1]
+	require.Equal(t, "pgx SELECT", selected.Name)
+	require.Equal(t, parent.SpanContext().SpanID(), selected.Parent.SpanID())
+	attrs := attributes(selected)
+	require.Equal(t, "postgresql", attrs[semconv.DBSystemKey].AsString())
+	require.Equal(t, int64(1), attrs[rowsAffectedKey].AsInt64())
+	require.Equal(t, "pgx INSERT", inserted.Name)
+	require.Equal(t, otelcodes.Error, inserted.Status.Code)
+	require.Len(t, inserted.Events, 1)
+}
+
+func TestOperation(t *testing.T) {
+	balance := &model.Balance{ProfileID: uuid.New(), Operation: decimal.NewFromFloat(-10)}
+	attrs := Operation(balance)
+	require.Contains(t, attrs, ProfileIDKey.String(balance.ProfileID.String()))
+	require.Contains(t, attrs, SignKey.Int(-1))
+}
+
+func TestConfigureWithoutEndpoint(t *testing.T) {
+	shutdown, err := Configure(context.Background(), "", false)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
@@ -3,18 +3,35 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"time"
 
 	"github.com/artnikel/BalanceService/internal/config"
 	"github.com/artnikel/BalanceService/internal/handler"
+	"github.com/artnikel/BalanceService/internal/health"
+	"github.com/artnikel/BalanceService/internal/logging"
+	"github.com/artnikel/BalanceService/internal/metrics"
+	"github.com/artnikel/BalanceService/internal/outbox"
 	"github.com/artnikel/BalanceService/internal/repository"
 	"github.com/artnikel/BalanceService/internal/service"
+	"github.com/artnikel/BalanceService/internal/tracing"
 	"github.com/artnikel/BalanceService/proto"
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// schedulerLock is the key of advisory lock which is held by the instance running schedules
+	schedulerLock = "balance-scheduler"
+	// httpReadHeaderTimeout limits the time of reading headers of requests to HTTP listener
+	httpReadHeaderTimeout = 5 * time.Second
 )
 
 func connectPostgres(connString string) (*pgxpool.Pool, error) {
@@ -22,6 +39,7 @@ func connectPostgres(connString string) (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, err
 	}
+	cfgPostgres.ConnConfig.Tracer = tracing.QueryTracer{}
 	dbpool, err := pgxpool.NewWithConfig(context.Background(), cfgPostgres)
 	if err != nil {
 		return nil, err
@@ -29,6 +47,147 @@ func connectPostgres(connString string) (*pgxpool.Pool, error) {
 	return dbpool, nil
 }
 
+// reconcile periodically compares balances of wallets with sums of their postings and reports the drifted ones
+func reconcile(ctx context.Context, srv *service.BalanceService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drifts, err := srv.Reconcile(ctx)
+			if err != nil {
+				logging.FromContext(ctx).WithError(err).Error("reconcile failed")
+				continue
+			}
+			for _, drift := range drifts {
+				logging.FromContext(ctx).WithField(logging.ProfileIDKey, drift.ProfileID).
+					Warnf("balance drift: currency %s ledger %s snapshot %s", drift.Currency, drift.Ledger, drift.Snapshot)
+			}
+		}
+	}
+}
+
+// expireHolds periodically marks holds which weren`t captured in time as expired
+func expireHolds(ctx context.Context, srv *service.BalanceService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := srv.ExpireHolds(ctx)
+			if err != nil {
+				logging.FromContext(ctx).WithError(err).Error("expiring of holds failed")
+				continue
+			}
+			if expired > 0 {
+				logging.FromContext(ctx).Infof("expired holds: %d", expired)
+			}
+		}
+	}
+}
+
+// listenBalanceChanges keeps listening for changes of balances for watchers and listens again after failures
+func listenBalanceChanges(ctx context.Context, srv *service.BalanceService, retryInterval time.Duration) {
+	for {
+		err := srv.ListenBalanceChanges(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).WithError(err).Error("listening of balance changes failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// relayOutbox periodically publishes events from outbox, batches are published one by one while outbox has due events
+func relayOutbox(ctx context.Context, relay *outbox.Relay, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				claimed, err := relay.PublishPending(ctx)
+				if err != nil {
+					logging.FromContext(ctx).WithError(err).Error("publishing of events failed")
+					break
+				}
+				if claimed < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// runSchedules keeps trying to take the lock of scheduler, only the instance which holds it runs due schedules
+func runSchedules(ctx context.Context, pgRep *repository.PgRepository, srv *service.BalanceService, cfg *config.Variables) {
+	ticker := time.NewTicker(cfg.ScheduleInterval)
+	defer ticker.Stop()
+	for {
+		_, err := pgRep.Lead(ctx, schedulerLock, cfg.LeaderCheckInterval, func(ctx context.Context) error {
+			return leadSchedules(ctx, srv, ticker, cfg.ScheduleBatchSize)
+		})
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Error("running of schedules stopped")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leadSchedules runs due schedules on every tick until the lock of scheduler is lost,
+// batches are run one by one while there are due schedules
+func leadSchedules(ctx context.Context, srv *service.BalanceService, ticker *time.Ticker, batchSize int) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			for {
+				due, err := srv.RunDueSchedules(ctx, batchSize)
+				if err != nil {
+					logging.FromContext(ctx).WithError(err).Error("running of schedules failed")
+					break
+				}
+				if due < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// worker returns context of background worker whose logs have its name
+func worker(name string) context.Context {
+	return logging.WithFields(context.Background(), logrus.Fields{logging.WorkerKey: name})
+}
+
+// serveHTTP serves metrics for Prometheus and probes of health for orchestrator on HTTP listener
+func serveHTTP(address string, checker *health.Checker) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", checker.Live)
+	mux.HandleFunc("/readyz", checker.Ready)
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: httpReadHeaderTimeout}
+	err := server.ListenAndServe()
+	if err != nil {
+		logrus.Errorf("failed to serve HTTP: %v", err)
+	}
+}
+
 // nolint gocritic
 func main() {
 	v := validator.New()
@@ -36,23 +195,60 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not parse config: %v", err)
 	}
+	err = logging.Configure(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("could not configure logging: %v", err)
+	}
+	shutdownTracing, err := tracing.Configure(context.Background(), cfg.TracingEndpoint, cfg.TracingInsecure)
+	if err != nil {
+		logrus.Fatalf("could not configure tracing: %v", err)
+	}
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to shut down tracing: %v", err)
+		}
+	}()
 	dbpool, errPool := connectPostgres(cfg.PostgresConnBalance)
 	if errPool != nil {
-		log.Fatalf("could not construct the pool: %v", errPool)
+		logrus.Fatalf("could not construct the pool: %v", errPool)
 	}
 	defer dbpool.Close()
+	prometheus.MustRegister(metrics.NewPoolCollector(dbpool))
+	checker := health.NewChecker(dbpool.Ping, cfg.HealthTimeout, proto.BalanceService_ServiceDesc.ServiceName)
+	go checker.Run(worker("health"), cfg.HealthInterval)
+	go serveHTTP(cfg.MetricsAddress, checker)
 	pgRep := repository.NewPgRepository(dbpool)
-	pgServ := service.NewBalanceService(pgRep)
+	pgServ := service.NewBalanceService(pgRep, cfg.HoldTTL)
+	go reconcile(worker("reconcile"), pgServ, cfg.ReconcileInterval)
+	go expireHolds(worker("expire-holds"), pgServ, cfg.HoldExpireInterval)
+	go listenBalanceChanges(worker("listen-balance-changes"), pgServ, cfg.ListenRetryInterval)
+	if cfg.NatsURL == "" {
+		logrus.Warn("NATS_URL is empty, balance events stay in outbox until broker is configured")
+	} else {
+		publisher, err := outbox.NewNATSPublisher(cfg.NatsURL, cfg.NatsTimeout)
+		if err != nil {
+			logrus.Fatalf("could not create publisher: %v", err)
+		}
+		defer publisher.Close()
+		relay := outbox.NewRelay(pgRep, publisher, cfg.OutboxBatchSize, cfg.OutboxMaxAttempts, cfg.OutboxRetryDelay)
+		go relayOutbox(worker("relay-outbox"), relay, cfg.OutboxInterval, cfg.OutboxBatchSize)
+	}
+	go runSchedules(worker("run-schedules"), pgRep, pgServ, cfg)
 	pgHandl := handler.NewEntityBalance(pgServ, v)
 	lis, err := net.Listen("tcp", cfg.BalanceAddress)
 	if err != nil {
-		log.Fatalf("cannot create listener: %s", err)
+		logrus.Fatalf("cannot create listener: %s", err)
 	}
-	fmt.Println("Balance Service started")
-	grpcServer := grpc.NewServer()
+	logrus.Info("Balance Service started")
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(), metrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(), metrics.StreamServerInterceptor()))
 	proto.RegisterBalanceServiceServer(grpcServer, pgHandl)
+	healthpb.RegisterHealthServer(grpcServer, checker.Server())
 	err = grpcServer.Serve(lis)
 	if err != nil {
-		log.Fatalf("failed to serve listener: %s", err)
+		logrus.Fatalf("failed to serve listener: %s", err)
 	}
 }
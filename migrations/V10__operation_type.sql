@@ -0,0 +1,13 @@
+ALTER TABLE balance ADD COLUMN operationtype varchar(32);
+ALTER TABLE balance ADD COLUMN description text NOT NULL DEFAULT '';
+ALTER TABLE balance ADD COLUMN metadata jsonb NOT NULL DEFAULT '{}';
+
+UPDATE balance SET operationtype = CASE
+	WHEN transferid IS NOT NULL THEN 'transfer'
+	WHEN operation < 0 THEN 'withdrawal'
+	ELSE 'deposit'
+END;
+
+ALTER TABLE balance ALTER COLUMN operationtype SET NOT NULL;
+
+CREATE INDEX balance_operationtype_idx ON balance (profileid, operationtype, operationtime);
@@ -0,0 +1,3 @@
+ALTER TABLE balance ADD COLUMN reversalof uuid REFERENCES balance (balanceid);
+
+CREATE UNIQUE INDEX balance_reversalof_idx ON balance (reversalof) WHERE reversalof IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE operation_limit (
+	profileid uuid NOT NULL,
+	currency varchar(3) NOT NULL,
+	direction varchar(16) NOT NULL,
+	period varchar(16) NOT NULL,
+	amount numeric NOT NULL CHECK (amount >= 0),
+	updatedtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (profileid, currency, direction, period)
+);
+
+CREATE INDEX balance_profileid_currency_operationtime_idx ON balance (profileid, currency, operationtime);
@@ -0,0 +1,21 @@
+CREATE TABLE accounts (
+	profileid uuid,
+	status varchar(16) NOT NULL DEFAULT 'active',
+	freezedeposits boolean NOT NULL DEFAULT false,
+	updatedtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (profileid)
+);
+
+CREATE TABLE account_audit (
+	auditid bigserial,
+	profileid uuid NOT NULL REFERENCES accounts (profileid),
+	oldstatus varchar(16) NOT NULL,
+	newstatus varchar(16) NOT NULL,
+	freezedeposits boolean NOT NULL,
+	actor varchar(128) NOT NULL,
+	reason text NOT NULL,
+	changedtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (auditid)
+);
+
+CREATE INDEX account_audit_profileid_idx ON account_audit (profileid, changedtime);
@@ -0,0 +1,7 @@
+CREATE TABLE credit_line (
+	profileid uuid NOT NULL,
+	currency varchar(3) NOT NULL,
+	creditlimit numeric NOT NULL CHECK (creditlimit >= 0),
+	updatedtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (profileid, currency)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE schedules (
+	scheduleid uuid,
+	profileid uuid NOT NULL,
+	operation numeric NOT NULL,
+	currency varchar(3) NOT NULL,
+	operationtype varchar(32) NOT NULL,
+	description text NOT NULL DEFAULT '',
+	expression varchar(128) NOT NULL,
+	status varchar(16) NOT NULL DEFAULT 'active',
+	nextruntime timestamp NOT NULL,
+	createdtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (scheduleid)
+);
+
+CREATE INDEX schedules_profileid_idx ON schedules (profileid, createdtime);
+CREATE INDEX schedules_due_idx ON schedules (nextruntime) WHERE status = 'active';
+
+CREATE TABLE schedule_runs (
+	runid uuid,
+	scheduleid uuid NOT NULL REFERENCES schedules (scheduleid),
+	runtime timestamp NOT NULL,
+	status varchar(16) NOT NULL,
+	errorcode varchar(64) NOT NULL DEFAULT '',
+	reason text NOT NULL DEFAULT '',
+	executedtime timestamp NOT NULL DEFAULT NOW(),
+	primary key (runid)
+);
+
+CREATE INDEX schedule_runs_scheduleid_idx ON schedule_runs (scheduleid, runtime);
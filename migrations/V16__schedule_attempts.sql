@@ -0,0 +1,2 @@
+-- attempts of the next run whose operation failed for reasons other than business rules
+ALTER TABLE schedules ADD COLUMN attempts int NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE balance ALTER COLUMN operation TYPE numeric USING operation::numeric;
@@ -0,0 +1 @@
+ALTER TABLE balance ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1 @@
+CREATE INDEX balance_profileid_operationtime_idx ON balance (profileid, operationtime, balanceid);
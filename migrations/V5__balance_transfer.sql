@@ -0,0 +1,2 @@
+ALTER TABLE balance ADD COLUMN transferid uuid;
+CREATE INDEX balance_transferid_idx ON balance (transferid) WHERE transferid IS NOT NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE balance_snapshot (
+	profileid uuid,
+	currency varchar(3),
+	money numeric NOT NULL DEFAULT 0,
+	updatedtime timestamp DEFAULT NOW(),
+	primary key (profileid, currency)
+);
+
+INSERT INTO balance_snapshot (profileid, currency, money)
+SELECT profileid, currency, SUM(operation) FROM balance GROUP BY profileid, currency;
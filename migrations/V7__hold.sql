@@ -0,0 +1,13 @@
+CREATE TABLE hold (
+	holdid uuid,
+	profileid uuid NOT NULL,
+	currency varchar(3) NOT NULL,
+	amount numeric NOT NULL,
+	captured numeric,
+	status varchar(16) NOT NULL DEFAULT 'active',
+	createdtime timestamp DEFAULT NOW(),
+	expirestime timestamp NOT NULL,
+	primary key (holdid)
+);
+
+CREATE INDEX hold_profileid_active_idx ON hold (profileid, currency) WHERE status = 'active';
@@ -0,0 +1,14 @@
+CREATE TABLE outbox (
+	eventid uuid,
+	topic varchar(64) NOT NULL,
+	payload jsonb NOT NULL,
+	status varchar(16) NOT NULL DEFAULT 'pending',
+	attempts integer NOT NULL DEFAULT 0,
+	lasterror text,
+	createdtime timestamp DEFAULT NOW(),
+	nextattempttime timestamp DEFAULT NOW(),
+	publishedtime timestamp,
+	primary key (eventid)
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (nextattempttime) WHERE status = 'pending';
@@ -0,0 +1,65 @@
+CREATE TABLE ledger_accounts (
+	accountid uuid DEFAULT gen_random_uuid(),
+	kind varchar(16) NOT NULL,
+	profileid uuid NOT NULL,
+	currency varchar(3) NOT NULL,
+	money numeric NOT NULL DEFAULT 0,
+	createdtime timestamp DEFAULT NOW(),
+	updatedtime timestamp DEFAULT NOW(),
+	primary key (accountid),
+	UNIQUE (kind, profileid, currency)
+);
+
+CREATE TABLE journal_entries (
+	entryid uuid,
+	createdtime timestamp DEFAULT NOW(),
+	primary key (entryid)
+);
+
+CREATE TABLE postings (
+	entryid uuid NOT NULL REFERENCES journal_entries (entryid),
+	accountid uuid NOT NULL REFERENCES ledger_accounts (accountid),
+	amount numeric NOT NULL,
+	primary key (entryid, accountid)
+);
+
+CREATE INDEX postings_accountid_idx ON postings (accountid);
+
+-- every operation recorded before double-entry model becomes an entry between wallet of profile
+-- and external settlement account which belongs to the service itself
+INSERT INTO ledger_accounts (kind, profileid, currency)
+SELECT DISTINCT 'wallet', profileid, currency FROM balance WHERE profileid IS NOT NULL;
+
+INSERT INTO ledger_accounts (kind, profileid, currency)
+SELECT DISTINCT 'settlement', '00000000-0000-0000-0000-000000000000'::uuid, currency FROM balance WHERE profileid IS NOT NULL;
+
+INSERT INTO journal_entries (entryid, createdtime)
+SELECT balanceid, operationtime FROM balance WHERE profileid IS NOT NULL;
+
+INSERT INTO postings (entryid, accountid, amount)
+SELECT b.balanceid, a.accountid, b.operation FROM balance b
+JOIN ledger_accounts a ON a.kind = 'wallet' AND a.profileid = b.profileid AND a.currency = b.currency;
+
+INSERT INTO postings (entryid, accountid, amount)
+SELECT b.balanceid, a.accountid, -b.operation FROM balance b
+JOIN ledger_accounts a ON a.kind = 'settlement' AND a.profileid = '00000000-0000-0000-0000-000000000000'::uuid AND a.currency = b.currency
+WHERE b.profileid IS NOT NULL;
+
+UPDATE ledger_accounts a SET money = p.money
+FROM (SELECT accountid, SUM(amount) AS money FROM postings GROUP BY accountid) p
+WHERE p.accountid = a.accountid AND a.kind = 'wallet';
+
+DROP TABLE balance_snapshot;
+
+-- postings of every entry must sum to zero, the check runs at commit when all postings of entry are inserted
+CREATE FUNCTION check_entry_balanced() RETURNS trigger AS $$
+BEGIN
+	IF (SELECT SUM(amount) FROM postings WHERE entryid = NEW.entryid) <> 0 THEN
+		RAISE EXCEPTION 'postings of entry % don''t sum to zero', NEW.entryid;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced AFTER INSERT ON postings
+DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION check_entry_balanced();
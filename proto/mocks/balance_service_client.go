@@ -5,11 +5,11 @@ package mocks
 import (
 	context "context"
 
-	grpc "google.golang.org/grpc"
+	proto "github.com/artnikel/BalanceService/proto"
 
 	mock "github.com/stretchr/testify/mock"
 
-	proto "github.com/artnikel/BalanceService/proto"
+	grpc "google.golang.org/grpc"
 )
 
 // BalanceServiceClient is an autogenerated mock type for the BalanceServiceClient type
@@ -47,6 +47,156 @@ func (_m *BalanceServiceClient) BalanceOperation(ctx context.Context, in *proto.
 	return r0, r1
 }
 
+// BatchBalanceOperations provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) BatchBalanceOperations(ctx context.Context, in *proto.BatchBalanceOperationsRequest, opts ...grpc.CallOption) (*proto.BatchBalanceOperationsResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.BatchBalanceOperationsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.BatchBalanceOperationsRequest, ...grpc.CallOption) *proto.BatchBalanceOperationsResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.BatchBalanceOperationsResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.BatchBalanceOperationsRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CancelSchedule provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) CancelSchedule(ctx context.Context, in *proto.CancelScheduleRequest, opts ...grpc.CallOption) (*proto.CancelScheduleResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.CancelScheduleResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.CancelScheduleRequest, ...grpc.CallOption) *proto.CancelScheduleResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.CancelScheduleResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.CancelScheduleRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Capture provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) Capture(ctx context.Context, in *proto.CaptureRequest, opts ...grpc.CallOption) (*proto.CaptureResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.CaptureResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.CaptureRequest, ...grpc.CallOption) *proto.CaptureResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.CaptureResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.CaptureRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CloseAccount provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) CloseAccount(ctx context.Context, in *proto.CloseAccountRequest, opts ...grpc.CallOption) (*proto.CloseAccountResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.CloseAccountResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.CloseAccountRequest, ...grpc.CallOption) *proto.CloseAccountResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.CloseAccountResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.CloseAccountRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FreezeAccount provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) FreezeAccount(ctx context.Context, in *proto.FreezeAccountRequest, opts ...grpc.CallOption) (*proto.FreezeAccountResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.FreezeAccountResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.FreezeAccountRequest, ...grpc.CallOption) *proto.FreezeAccountResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.FreezeAccountResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.FreezeAccountRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBalance provides a mock function with given fields: ctx, in, opts
 func (_m *BalanceServiceClient) GetBalance(ctx context.Context, in *proto.GetBalanceRequest, opts ...grpc.CallOption) (*proto.GetBalanceResponse, error) {
 	_va := make([]interface{}, len(opts))
@@ -77,6 +227,366 @@ func (_m *BalanceServiceClient) GetBalance(ctx context.Context, in *proto.GetBal
 	return r0, r1
 }
 
+// GetLimits provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) GetLimits(ctx context.Context, in *proto.GetLimitsRequest, opts ...grpc.CallOption) (*proto.GetLimitsResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.GetLimitsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.GetLimitsRequest, ...grpc.CallOption) *proto.GetLimitsResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.GetLimitsResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.GetLimitsRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Hold provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) Hold(ctx context.Context, in *proto.HoldRequest, opts ...grpc.CallOption) (*proto.HoldResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.HoldResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.HoldRequest, ...grpc.CallOption) *proto.HoldResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.HoldResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.HoldRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOperations provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) ListOperations(ctx context.Context, in *proto.ListOperationsRequest, opts ...grpc.CallOption) (*proto.ListOperationsResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.ListOperationsResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.ListOperationsRequest, ...grpc.CallOption) *proto.ListOperationsResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.ListOperationsResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.ListOperationsRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSchedules provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) ListSchedules(ctx context.Context, in *proto.ListSchedulesRequest, opts ...grpc.CallOption) (*proto.ListSchedulesResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.ListSchedulesResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.ListSchedulesRequest, ...grpc.CallOption) *proto.ListSchedulesResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.ListSchedulesResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.ListSchedulesRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Release provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) Release(ctx context.Context, in *proto.ReleaseRequest, opts ...grpc.CallOption) (*proto.ReleaseResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.ReleaseResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.ReleaseRequest, ...grpc.CallOption) *proto.ReleaseResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.ReleaseResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.ReleaseRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReverseOperation provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) ReverseOperation(ctx context.Context, in *proto.ReverseOperationRequest, opts ...grpc.CallOption) (*proto.ReverseOperationResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.ReverseOperationResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.ReverseOperationRequest, ...grpc.CallOption) *proto.ReverseOperationResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.ReverseOperationResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.ReverseOperationRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScheduleOperation provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) ScheduleOperation(ctx context.Context, in *proto.ScheduleOperationRequest, opts ...grpc.CallOption) (*proto.ScheduleOperationResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.ScheduleOperationResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.ScheduleOperationRequest, ...grpc.CallOption) *proto.ScheduleOperationResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.ScheduleOperationResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.ScheduleOperationRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetCreditLimit provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) SetCreditLimit(ctx context.Context, in *proto.SetCreditLimitRequest, opts ...grpc.CallOption) (*proto.SetCreditLimitResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.SetCreditLimitResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.SetCreditLimitRequest, ...grpc.CallOption) *proto.SetCreditLimitResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.SetCreditLimitResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.SetCreditLimitRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetLimit provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) SetLimit(ctx context.Context, in *proto.SetLimitRequest, opts ...grpc.CallOption) (*proto.SetLimitResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.SetLimitResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.SetLimitRequest, ...grpc.CallOption) *proto.SetLimitResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.SetLimitResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.SetLimitRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transfer provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) Transfer(ctx context.Context, in *proto.TransferRequest, opts ...grpc.CallOption) (*proto.TransferResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.TransferResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.TransferRequest, ...grpc.CallOption) *proto.TransferResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.TransferResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.TransferRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UnfreezeAccount provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) UnfreezeAccount(ctx context.Context, in *proto.UnfreezeAccountRequest, opts ...grpc.CallOption) (*proto.UnfreezeAccountResponse, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *proto.UnfreezeAccountResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.UnfreezeAccountRequest, ...grpc.CallOption) *proto.UnfreezeAccountResponse); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proto.UnfreezeAccountResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.UnfreezeAccountRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WatchBalance provides a mock function with given fields: ctx, in, opts
+func (_m *BalanceServiceClient) WatchBalance(ctx context.Context, in *proto.WatchBalanceRequest, opts ...grpc.CallOption) (proto.BalanceService_WatchBalanceClient, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 proto.BalanceService_WatchBalanceClient
+	if rf, ok := ret.Get(0).(func(context.Context, *proto.WatchBalanceRequest, ...grpc.CallOption) proto.BalanceService_WatchBalanceClient); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(proto.BalanceService_WatchBalanceClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *proto.WatchBalanceRequest, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewBalanceServiceClient interface {
 	mock.TestingT
 	Cleanup(func())